@@ -2,26 +2,78 @@ package cmd
 
 import (
 	"fmt"
+	"io/fs"
 	"iter"
-	"log"
 	"os"
+	"path/filepath"
 	"quinto/core"
 	"quinto/data"
+	"quinto/persistence"
+	"quinto/search"
 	"quinto/stemming"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
+const defaultIndexDirectory = ".quinto"
+const defaultMaxCachedChunks = 1024
+const defaultMaxChunkSize = 4096
+const inlineDocumentSource = "<inline>"
+const autoLanguage = "auto"
+const defaultLanguage = "eng"
+const languageDetectionPrefixSize = 4096
+const bodyFieldName = "body"
+
+var languageAnalyzers = map[string]string{
+	"eng": "english",
+	"ita": "italian",
+	"":    "english",
+}
+
+var fieldNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+type inputDocument struct {
+	source   string
+	text     string
+	language string
+	tokens   iter.Seq[core.Token]
+	nGrams   iter.Seq[core.Token]
+	fields   []core.DocumentField
+}
+
 func ValidateInputFlags(cmd *cobra.Command, args []string) error {
 	asInlineText, _ := cmd.Flags().GetString("inline")
-	asFilePath, _ := cmd.Flags().GetString("filepath")
+	asFilePaths, _ := cmd.Flags().GetStringSlice("filepath")
 
-	if len(asInlineText) > 0 && len(asFilePath) > 0 {
-		return fmt.Errorf("conflicting flags: --as-inline-text and --as-file-path cannot be set at the same time")
+	if len(asInlineText) > 0 && len(asFilePaths) > 0 {
+		return fmt.Errorf("conflicting flags: --inline and --filepath cannot be set at the same time")
 	}
 
-	if len(asInlineText)+len(asFilePath) == 0 {
-		return fmt.Errorf("missing flags: --as-inline-text or --as-file-path must be set")
+	if len(asInlineText) == 0 && len(asFilePaths) == 0 {
+		return fmt.Errorf("missing flags: --inline or --filepath must be set")
+	}
+
+	if externalId, _ := cmd.Flags().GetString("id"); externalId != "" && len(asFilePaths) > 0 {
+		return fmt.Errorf("conflicting flags: --id can only be set together with --inline")
+	}
+
+	if _, err := newAnalyzer(cmd); err != nil {
+		return err
+	}
+
+	if _, err := newFieldAnalyzers(cmd); err != nil {
+		return err
+	}
+
+	if _, err := parseDocumentFields(cmd); err != nil {
+		return err
+	}
+
+	if _, err := newFieldBoosts(cmd); err != nil {
+		return err
 	}
 
 	return nil
@@ -29,34 +81,263 @@ func ValidateInputFlags(cmd *cobra.Command, args []string) error {
 
 func RegisterInputFlags(cmd *cobra.Command) {
 	cmd.Flags().String("inline", "", "Treat inputs as inline text")
-	cmd.Flags().String("filepath", "", "Treat inputs as local file-paths")
-	cmd.Flags().String("lang", "eng", "Select language: eng->English")
+	cmd.Flags().StringSlice("filepath", nil, "Treat inputs as local file-paths (directories are walked recursively)")
+	RegisterAnalysisFlags(cmd)
 }
 
-func IterateTokens(cmd *cobra.Command, args []string) iter.Seq[core.Token] {
-	asInlineText, _ := cmd.Flags().GetString("inline")
-	asFilePath, _ := cmd.Flags().GetString("filepath")
+func RegisterAnalysisFlags(cmd *cobra.Command) {
+	cmd.Flags().String("lang", autoLanguage, "Select language: auto->detected, eng->English, ita->Italian")
+	cmd.Flags().String("analyzer", "", "Select analyzer by name (overrides --lang): "+strings.Join(stemming.AnalyzerNames(), ", "))
+	cmd.Flags().StringToString("field-analyzer", nil, "Select the analyzer of a field by name (e.g. --field-analyzer title=whitespace)")
+}
+
+func RegisterIndexFlags(cmd *cobra.Command) {
+	cmd.Flags().String("index-dir", defaultIndexDirectory, "Directory in which the index is stored")
+}
+
+func OpenIndex(cmd *cobra.Command) (*persistence.PersistenceManager, error) {
+	indexDirectory, _ := cmd.Flags().GetString("index-dir")
+	handler, err := persistence.NewFileSystemDiskHandler(indexDirectory)
+	if err != nil {
+		return nil, err
+	}
+	return persistence.NewPersistenceManager(persistence.PersistenceConfig{
+		MaxCachedChunks: defaultMaxCachedChunks,
+		MaxChunkSize:    defaultMaxChunkSize,
+		IoHandler:       handler,
+	}), nil
+}
+
+func isLanguageDetected(cmd *cobra.Command) bool {
+	name, _ := cmd.Flags().GetString("analyzer")
+	lang, _ := cmd.Flags().GetString("lang")
+	return name == "" && lang == autoLanguage
+}
+
+func newLanguageAnalyzer(lang string) (stemming.Analyzer, error) {
+	name, exists := languageAnalyzers[lang]
+	if !exists {
+		return stemming.Analyzer{}, fmt.Errorf("unsupported language: %s", lang)
+	}
+	analyzer, exists := stemming.LookupAnalyzer(name)
+	if !exists {
+		return analyzer, fmt.Errorf("unsupported analyzer: %s", name)
+	}
+	return analyzer, nil
+}
+
+// The analyzer selected by the flags: when the language has to be detected, this is
+// the analyzer of the default language (used for texts whose language is unknown).
+func newAnalyzer(cmd *cobra.Command) (stemming.Analyzer, error) {
+	name, _ := cmd.Flags().GetString("analyzer")
+	if name == "" {
+		lang, _ := cmd.Flags().GetString("lang")
+		if lang == autoLanguage {
+			lang = defaultLanguage
+		}
+		return newLanguageAnalyzer(lang)
+	}
+	analyzer, exists := stemming.LookupAnalyzer(name)
+	if !exists {
+		return analyzer, fmt.Errorf("unsupported analyzer: %s", name)
+	}
+	return analyzer, nil
+}
+
+func validateFieldName(name string) error {
+	if !fieldNameRegex.MatchString(name) || name == bodyFieldName {
+		return fmt.Errorf("invalid field name: %s", name)
+	}
+	return nil
+}
+
+func newFieldAnalyzers(cmd *cobra.Command) (map[string]stemming.Analyzer, error) {
+	names, _ := cmd.Flags().GetStringToString("field-analyzer")
+	analyzers := map[string]stemming.Analyzer{}
+	for field, name := range names {
+		if err := validateFieldName(field); err != nil {
+			return nil, err
+		}
+		analyzer, exists := stemming.LookupAnalyzer(name)
+		if !exists {
+			return nil, fmt.Errorf("unsupported analyzer: %s", name)
+		}
+		analyzers[field] = analyzer
+	}
+	return analyzers, nil
+}
+
+func newFieldBoosts(cmd *cobra.Command) (map[string]float64, error) {
+	values, _ := cmd.Flags().GetStringToString("field-boost")
+	boosts := map[string]float64{}
+	for field, value := range values {
+		if field != bodyFieldName {
+			if err := validateFieldName(field); err != nil {
+				return nil, err
+			}
+		}
+		boost, err := strconv.ParseFloat(value, 64)
+		if err != nil || boost <= 0 {
+			return nil, fmt.Errorf("invalid boost of field %s: %s (expected a positive number)", field, value)
+		}
+		boosts[field] = boost
+	}
+	return boosts, nil
+}
+
+type documentFieldText struct {
+	name string
+	text string
+}
+
+func parseDocumentFields(cmd *cobra.Command) ([]documentFieldText, error) {
+	pairs, _ := cmd.Flags().GetStringArray("field")
+	fields := []documentFieldText{}
+	for _, pair := range pairs {
+		name, text, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid field: %s (expected name=text)", pair)
+		}
+		if err := validateFieldName(name); err != nil {
+			return nil, err
+		}
+		fields = append(fields, documentFieldText{name: name, text: text})
+	}
+	return fields, nil
+}
+
+// The language of the documents, as stored along with them: it is empty when an
+// analyzer has been explicitly selected, since it may not be bound to any language.
+func selectedLanguage(cmd *cobra.Command) string {
+	name, _ := cmd.Flags().GetString("analyzer")
 	lang, _ := cmd.Flags().GetString("lang")
+	if name != "" {
+		return ""
+	}
+	if lang == "" {
+		return defaultLanguage
+	}
+	return lang
+}
 
-	var sourceTextIterator iter.Seq[string]
+func detectLanguage(text string) string {
+	if language, detected := stemming.DetectLanguage(text); detected {
+		if _, supported := languageAnalyzers[language]; supported {
+			return language
+		}
+	}
+	return defaultLanguage
+}
+
+func detectTextLanguage(text string) string {
+	return detectLanguage(text[:min(len(text), languageDetectionPrefixSize)])
+}
+
+func expandFilePaths(filePaths []string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for _, rootPath := range filePaths {
+			stopped := false
+			err := filepath.WalkDir(rootPath, func(path string, entry fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !entry.Type().IsRegular() {
+					return nil
+				}
+				if !yield(path, nil) {
+					stopped = true
+					return filepath.SkipAll
+				}
+				return nil
+			})
+			if stopped {
+				return
+			}
+			if err != nil {
+				yield("", err)
+				return
+			}
+		}
+	}
+}
 
-	if len(asInlineText) > 0 {
-		sourceTextIterator = data.NewStringIterator(asInlineText)
-	} else {
-		file, err := os.Open(asFilePath)
-		if err != nil {
-			log.Fatal(err)
+func newInputDocument(cmd *cobra.Command, source string, text string) (inputDocument, error) {
+	document := inputDocument{source: source, text: text, language: selectedLanguage(cmd)}
+	if isLanguageDetected(cmd) {
+		document.language = detectTextLanguage(text)
+	}
+	analyzer, err := newAnalyzer(cmd)
+	if document.language != "" {
+		analyzer, err = newLanguageAnalyzer(document.language)
+	}
+	if err != nil {
+		return document, err
+	}
+	document.tokens = analyzer.Analyze(data.NewTextSpanIterator(text))
+	if withNGrams, _ := cmd.Flags().GetBool("ngrams"); withNGrams {
+		nGramFilter := stemming.NewSubstringNGramFilter(search.SubstringNGramSize)
+		words := data.NewTextSpanIterator(text)
+		document.nGrams = nGramFilter(stemming.AlignWordsWithTokens(words, analyzer.Analyze(words)))
+	}
+	fields, _ := parseDocumentFields(cmd)
+	fieldAnalyzers, _ := newFieldAnalyzers(cmd)
+	fieldBoosts, _ := newFieldBoosts(cmd)
+	if len(fields) > 0 {
+		document.fields = []core.DocumentField{{Name: bodyFieldName, Tokens: document.tokens, Boost: fieldBoosts[bodyFieldName]}}
+	}
+	for _, field := range fields {
+		fieldAnalyzer, exists := fieldAnalyzers[field.name]
+		if !exists {
+			fieldAnalyzer = analyzer
 		}
-		defer file.Close()
-		sourceTextIterator = data.NewFileReaderIterator(file)
+		document.fields = append(document.fields, core.DocumentField{
+			Name:   field.name,
+			Tokens: fieldAnalyzer.Analyze(data.NewTextSpanIterator(field.text)),
+			Boost:  fieldBoosts[field.name],
+		})
 	}
+	return document, nil
+}
+
+func IterateDocuments(cmd *cobra.Command, args []string) iter.Seq2[inputDocument, error] {
+	asInlineText, _ := cmd.Flags().GetString("inline")
+	asFilePaths, _ := cmd.Flags().GetStringSlice("filepath")
 
-	switch lang {
-	case "eng":
-		return stemming.NewEnglishTokenIterator(sourceTextIterator)
-	case "":
-		return stemming.NewEnglishTokenIterator(sourceTextIterator)
+	return func(yield func(inputDocument, error) bool) {
+		if len(asInlineText) > 0 {
+			yield(newInputDocument(cmd, inlineDocumentSource, asInlineText))
+			return
+		}
+		for filePath, err := range expandFilePaths(asFilePaths) {
+			if err != nil {
+				yield(inputDocument{}, err)
+				return
+			}
+			text, err := os.ReadFile(filePath)
+			if err != nil {
+				yield(inputDocument{source: filePath}, err)
+				return
+			}
+			document, err := newInputDocument(cmd, filePath, string(text))
+			if !yield(document, err) || err != nil {
+				return
+			}
+		}
 	}
+}
 
-	panic(fmt.Sprintf("Unsupported language: %s", lang))
+func IterateTokens(cmd *cobra.Command, args []string) iter.Seq2[core.Token, error] {
+	return func(yield func(core.Token, error) bool) {
+		for document, err := range IterateDocuments(cmd, args) {
+			if err != nil {
+				yield(core.Token{}, err)
+				return
+			}
+			for token := range document.tokens {
+				if !yield(token, nil) {
+					return
+				}
+			}
+		}
+	}
 }
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"quinto/core"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete <document-id>...",
+	Short: "Used to delete documents from the database",
+	Args:  cobra.MinimumNArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		docIds, err := parseDocumentIds(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+		index, err := OpenIndex(cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = deleteDocuments(index, docIds)
+		if compact, _ := cmd.Flags().GetBool("compact"); compact && err == nil {
+			index.Compact()
+		}
+		if closeErr := index.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func parseDocumentIds(args []string) ([]core.DocumentId, error) {
+	docIds := []core.DocumentId{}
+	for _, arg := range args {
+		docId, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid document-id: %s", arg)
+		}
+		docIds = append(docIds, core.DocumentId(docId))
+	}
+	return docIds, nil
+}
+
+func deleteDocuments(index core.ReverseIndex, docIds []core.DocumentId) error {
+	for _, docId := range docIds {
+		if err := index.DeleteDocument(docId); err != nil {
+			return err
+		}
+		fmt.Printf("%d\tdeleted\n", docId)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(deleteCmd)
+	RegisterIndexFlags(deleteCmd)
+	deleteCmd.Flags().Bool("compact", false, "Drop the deleted documents from the index right away")
+}
@@ -1,20 +1,319 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"iter"
+	"log"
+	"maps"
+	"os"
+	"os/signal"
+	"quinto/core"
+	"quinto/data"
+	"quinto/persistence"
+	"quinto/search"
+	"quinto/stemming"
+	"slices"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
+type searchHit struct {
+	DocId      core.DocumentId     `json:"docId"`
+	ExternalId string              `json:"externalId,omitempty"`
+	Score      float64             `json:"score"`
+	Positions  []core.TermPosition `json:"positions"`
+	Metadata   map[string]string   `json:"metadata,omitempty"`
+	Text       string              `json:"text,omitempty"`
+	Fragments  []string            `json:"fragments,omitempty"`
+}
+
+type searchOutputOptions struct {
+	format         string
+	withText       bool
+	analyzer       stemming.Analyzer
+	detectLanguage bool
+	highlight      *search.HighlightConfig
+	explanation    string
+}
+
+type explainedSearchHits struct {
+	Query string      `json:"query"`
+	Hits  []searchHit `json:"hits"`
+}
+
 var searchCmd = &cobra.Command{
-	Use:   "search",
+	Use:   "search <query>",
 	Short: "Used to search documents in the database",
+	Args:  cobra.MinimumNArgs(1),
+
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return ValidateSearchFlags(cmd, args)
+	},
 
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("search called")
+		index, err := OpenIndex(cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = searchDocuments(cmd, index, strings.Join(args, " "))
+		if closeErr := index.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
 	},
 }
 
+func searchDocuments(cmd *cobra.Command, index *persistence.PersistenceManager, queryString string) error {
+	query, err := prepareQuery(cmd, queryString, index.StoredLanguages())
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+
+	limit, _ := cmd.Flags().GetInt("limit")
+	resultSet := search.NewBoundedResultSet(limit)
+	config := search.ExecutionConfig{Scorer: newBM25Scorer(cmd, index)}
+	if err := search.ExecuteContext(ctx, query, index, resultSet, config); err != nil {
+		return err
+	}
+
+	results := resultSet.SortedSlice()
+	if err := search.AttachStoredDocuments(results, index); err != nil {
+		return err
+	}
+
+	options := newSearchOutputOptions(cmd)
+	if explain, _ := cmd.Flags().GetBool("explain"); explain {
+		options.explanation = search.ExplainQuery(query)
+	}
+	return printSearchHits(options, results, index.ExternalId)
+}
+
+func ValidateSearchFlags(cmd *cobra.Command, args []string) error {
+	limit, _ := cmd.Flags().GetInt("limit")
+	format, _ := cmd.Flags().GetString("format")
+	k1, _ := cmd.Flags().GetFloat64("bm25-k1")
+	b, _ := cmd.Flags().GetFloat64("bm25-b")
+
+	if limit <= 0 {
+		return fmt.Errorf("invalid flag: --limit must be a positive number")
+	}
+
+	if format != "text" && format != "json" {
+		return fmt.Errorf("invalid flag: --format must be either 'text' or 'json'")
+	}
+
+	if highlight, _ := cmd.Flags().GetString("highlight"); !slices.Contains([]string{"none", "ansi", "html"}, highlight) {
+		return fmt.Errorf("invalid flag: --highlight must be one of 'none', 'ansi' or 'html'")
+	}
+
+	if _, err := newAnalyzer(cmd); err != nil {
+		return err
+	}
+
+	if _, err := loadSynonyms(cmd); err != nil {
+		return err
+	}
+
+	if _, err := newFieldAnalyzers(cmd); err != nil {
+		return err
+	}
+
+	if k1 < 0 {
+		return fmt.Errorf("invalid flag: --bm25-k1 must not be negative")
+	}
+
+	if b < 0 || b > 1 {
+		return fmt.Errorf("invalid flag: --bm25-b must be between 0 and 1")
+	}
+
+	return nil
+}
+
+func newQueryAnalyzer(analyzer stemming.Analyzer) search.QueryAnalyzer {
+	return func(source iter.Seq[string]) iter.Seq[core.Token] {
+		return analyzer.Analyze(data.NewTextSpanIterator(strings.Join(slices.Collect(source), " ")))
+	}
+}
+
+func newQueryAnalyzers(cmd *cobra.Command, storedLanguages []string) ([]search.QueryAnalyzer, error) {
+	if !isLanguageDetected(cmd) || len(storedLanguages) == 0 {
+		analyzer, err := newAnalyzer(cmd)
+		return []search.QueryAnalyzer{newQueryAnalyzer(analyzer)}, err
+	}
+	analyzers := []search.QueryAnalyzer{}
+	for _, language := range storedLanguages {
+		analyzer, err := newLanguageAnalyzer(language)
+		if err != nil {
+			return nil, err
+		}
+		analyzers = append(analyzers, newQueryAnalyzer(analyzer))
+	}
+	return analyzers, nil
+}
+
+func loadSynonyms(cmd *cobra.Command) (search.SynonymDictionary, error) {
+	synonymsPath, _ := cmd.Flags().GetString("synonyms")
+	if synonymsPath == "" {
+		return search.SynonymDictionary{}, nil
+	}
+	file, err := os.Open(synonymsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return search.LoadSynonymDictionary(file)
+}
+
+func prepareQuery(cmd *cobra.Command, queryString string, storedLanguages []string) (core.Query, error) {
+	analyzers, err := newQueryAnalyzers(cmd, storedLanguages)
+	if err != nil {
+		return nil, err
+	}
+	synonyms, err := loadSynonyms(cmd)
+	if err != nil {
+		return nil, err
+	}
+	fragments, err := search.SplitQuery(queryString)
+	if err != nil {
+		return nil, err
+	}
+	fieldAnalyzers := map[string][]search.QueryAnalyzer{}
+	fieldAnalyzersByName, err := newFieldAnalyzers(cmd)
+	if err != nil {
+		return nil, err
+	}
+	for field, analyzer := range fieldAnalyzersByName {
+		fieldAnalyzers[field] = []search.QueryAnalyzer{newQueryAnalyzer(analyzer)}
+		analyzers = append(analyzers, fieldAnalyzers[field]...)
+	}
+	fragments = search.ExpandSynonyms(fragments, synonyms, analyzers)
+	fragments = search.AnalyzeFieldedQuery(fragments, analyzers, fieldAnalyzers)
+	return search.ParseQuery(fragments)
+}
+
+func newBM25Scorer(cmd *cobra.Command, stats core.IndexStatistics) search.Scorer {
+	k1, _ := cmd.Flags().GetFloat64("bm25-k1")
+	b, _ := cmd.Flags().GetFloat64("bm25-b")
+	return search.NewBM25Scorer(stats, search.BM25Config{K1: k1, B: b})
+}
+
+func newSearchOutputOptions(cmd *cobra.Command) searchOutputOptions {
+	options := searchOutputOptions{}
+	options.format, _ = cmd.Flags().GetString("format")
+	options.withText, _ = cmd.Flags().GetBool("with-text")
+	options.analyzer, _ = newAnalyzer(cmd)
+	options.detectLanguage = isLanguageDetected(cmd)
+	switch highlight, _ := cmd.Flags().GetString("highlight"); highlight {
+	case "ansi":
+		options.highlight = &search.AnsiHighlightConfig
+	case "html":
+		options.highlight = &search.HtmlHighlightConfig
+	}
+	return options
+}
+
+func highlightSearchResult(result core.SearchResult, options searchOutputOptions) []string {
+	analyzer := options.analyzer
+	if options.detectLanguage && result.Document.Language != "" {
+		if languageAnalyzer, err := newLanguageAnalyzer(result.Document.Language); err == nil {
+			analyzer = languageAnalyzer
+		}
+	}
+	tokens := analyzer.Analyze(data.NewTextSpanIterator(result.Document.Text))
+	fragments := []string{}
+	for _, fragment := range search.Highlight(result.Document.Text, tokens, result.InvolvedTokens, *options.highlight) {
+		fragments = append(fragments, fragment.Text)
+	}
+	return fragments
+}
+
+func newSearchHit(
+	result core.SearchResult,
+	externalIdOf func(core.DocumentId) (string, bool),
+	options searchOutputOptions,
+) searchHit {
+	positions := []core.TermPosition{}
+	for token := range result.InvolvedTokens.Iterate() {
+		positions = append(positions, token.Position)
+	}
+	slices.Sort(positions)
+	externalId, _ := externalIdOf(result.DocId)
+	hit := searchHit{
+		DocId:      result.DocId,
+		ExternalId: externalId,
+		Score:      result.Score,
+		Positions:  slices.Compact(positions),
+	}
+	if result.Document != nil {
+		hit.Metadata = result.Document.Metadata
+		if options.withText {
+			hit.Text = result.Document.Text
+		}
+		if options.highlight != nil {
+			hit.Fragments = highlightSearchResult(result, options)
+		}
+	}
+	return hit
+}
+
+func formatMetadata(metadata map[string]string) string {
+	pairs := []string{}
+	for _, key := range slices.Sorted(maps.Keys(metadata)) {
+		pairs = append(pairs, key+"="+metadata[key])
+	}
+	return strings.Join(pairs, " ")
+}
+
+func printSearchHits(
+	options searchOutputOptions,
+	results []core.SearchResult,
+	externalIdOf func(core.DocumentId) (string, bool),
+) error {
+	hits := []searchHit{}
+	for _, result := range results {
+		hits = append(hits, newSearchHit(result, externalIdOf, options))
+	}
+	if options.format == "json" {
+		var output any = hits
+		if options.explanation != "" {
+			output = explainedSearchHits{Query: options.explanation, Hits: hits}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(output)
+	}
+	fmt.Print(options.explanation)
+	for _, hit := range hits {
+		fmt.Printf("%d\tscore=%.4f\tpositions=%v\t%s\t%s\n",
+			hit.DocId, hit.Score, hit.Positions, hit.ExternalId, formatMetadata(hit.Metadata))
+		for _, fragment := range hit.Fragments {
+			fmt.Printf("\t... %s ...\n", strings.Join(strings.Fields(fragment), " "))
+		}
+		if hit.Text != "" {
+			fmt.Println(hit.Text)
+		}
+	}
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(searchCmd)
+	RegisterIndexFlags(searchCmd)
+	RegisterAnalysisFlags(searchCmd)
+	searchCmd.Flags().Int("limit", 10, "Maximum number of results to print")
+	searchCmd.Flags().String("format", "text", "Output format: text, json")
+	searchCmd.Flags().Bool("with-text", false, "Print the original text of the documents")
+	searchCmd.Flags().String("highlight", "none", "Print the best fragments of the documents, highlighted with: none, ansi, html")
+	searchCmd.Flags().String("synonyms", "", "Synonyms file, one rule per line (e.g. 'car, automobile' or 'ny => new york')")
+	searchCmd.Flags().Bool("explain", false, "Print the query tree, as rewritten before being executed")
+	searchCmd.Flags().Float64("bm25-k1", search.DefaultBM25Config.K1, "BM25 term frequency saturation")
+	searchCmd.Flags().Float64("bm25-b", search.DefaultBM25Config.B, "BM25 document length normalization")
 }
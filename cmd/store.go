@@ -2,10 +2,20 @@ package cmd
 
 import (
 	"fmt"
+	"iter"
+	"log"
+	"maps"
+	"path/filepath"
+	"quinto/core"
 
 	"github.com/spf13/cobra"
 )
 
+type documentDescription struct {
+	externalId string
+	stored     core.StoredDocument
+}
+
 var storeCmd = &cobra.Command{
 	Use:   "store",
 	Short: "Used to store documents in the database",
@@ -15,11 +25,88 @@ var storeCmd = &cobra.Command{
 	},
 
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("store called")
+		index, err := OpenIndex(cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = storeDocuments(index, IterateDocuments(cmd, args), func(document inputDocument) (documentDescription, error) {
+			return describeDocument(cmd, document)
+		})
+		if closeErr := index.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
 	},
 }
 
+func documentExternalId(source string, externalId string) string {
+	if source != inlineDocumentSource {
+		if absolutePath, err := filepath.Abs(source); err == nil {
+			return absolutePath
+		}
+		return source
+	}
+	return externalId
+}
+
+func describeDocument(cmd *cobra.Command, document inputDocument) (documentDescription, error) {
+	externalId, _ := cmd.Flags().GetString("id")
+	metadata, _ := cmd.Flags().GetStringToString("meta")
+
+	description := documentDescription{
+		externalId: documentExternalId(document.source, externalId),
+		stored: core.StoredDocument{
+			Text:     document.text,
+			Language: document.language,
+			Metadata: maps.Clone(metadata),
+		},
+	}
+	if description.stored.Metadata == nil {
+		description.stored.Metadata = make(map[string]string)
+	}
+	if document.source != inlineDocumentSource {
+		description.stored.Metadata["path"] = document.source
+	}
+	return description, nil
+}
+
+func storeDocuments(
+	index core.DocumentIndexer,
+	documents iter.Seq2[inputDocument, error],
+	describe func(document inputDocument) (documentDescription, error),
+) error {
+	for document, err := range documents {
+		if err != nil {
+			return err
+		}
+		description, err := describe(document)
+		if err != nil {
+			return err
+		}
+		docId, err := index.IndexDocument(core.IndexedDocument{
+			ExternalId: description.externalId,
+			Tokens:     document.tokens,
+			Fields:     document.fields,
+			NGrams:     document.nGrams,
+			Stored:     description.stored,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d\t%s\t%s\n", docId, document.source, description.stored.Language)
+	}
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(storeCmd)
 	RegisterInputFlags(storeCmd)
+	RegisterIndexFlags(storeCmd)
+	storeCmd.Flags().String("id", "", "External id of the inline document (replaces any document with the same id)")
+	storeCmd.Flags().Bool("ngrams", false, "Index the character n-grams of the documents, to be found by substring (e.g. 'xk-4521')")
+	storeCmd.Flags().StringArray("field", nil, "Additional field of the documents, besides their body (e.g. --field title=Guitars)")
+	storeCmd.Flags().StringToString("field-boost", nil, "Boost the matches within a field, at search time (e.g. --field-boost title=2.5)")
+	storeCmd.Flags().StringToString("meta", nil, "Metadata to be stored along with the documents (e.g. --meta title=Notes)")
 }
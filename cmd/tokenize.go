@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"log"
 
 	"github.com/spf13/cobra"
 )
@@ -15,7 +16,16 @@ var tokenizeCmd = &cobra.Command{
 	},
 
 	Run: func(cmd *cobra.Command, args []string) {
-		for token := range IterateTokens(cmd, args) {
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		for token, err := range IterateTokens(cmd, args) {
+			if err != nil {
+				log.Fatal(err)
+			}
+			if verbose {
+				fmt.Printf("%d\t%d:%d\t%s\t%s\n",
+					token.Position, token.StartOffset, token.EndOffset, token.OriginalText, token.StemmedText)
+				continue
+			}
 			fmt.Printf("[%s] ", token.StemmedText)
 		}
 
@@ -25,4 +35,5 @@ var tokenizeCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(tokenizeCmd)
 	RegisterInputFlags(tokenizeCmd)
+	tokenizeCmd.Flags().Bool("verbose", false, "Print the position, the source offsets and the original text of every token")
 }
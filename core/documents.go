@@ -0,0 +1,36 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+An "IndexedDocument" gathers everything that is indexed about a single document: its
+tokens (or its "Fields", when it is made of many of them, in which case "Tokens" is
+ignored), the character n-grams to be found by substring (if any), and the
+"StoredDocument" to be given back along with the search results. A "DocumentIndexer"
+is every index able to apply all of them as a single update: the document becomes
+visible (and, for a persistent index, it is published on disk) together with its
+stored fields and n-grams, or not at all. When the "ExternalId" is set, the document
+replaces the one previously indexed under the same external id, if any, exactly like
+"UpsertDocument" does.
+==================================================================================*/
+
+package core
+
+import (
+	"iter"
+)
+
+type IndexedDocument struct {
+	ExternalId string
+	Tokens     iter.Seq[Token]
+	Fields     []DocumentField
+	NGrams     iter.Seq[Token]
+	Stored     StoredDocument
+}
+
+type DocumentIndexer interface {
+	IndexDocument(document IndexedDocument) (DocumentId, error)
+}
@@ -0,0 +1,63 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+A document can be made of many named fields (e.g. "title" and "body"), each one with
+its own tokens (usually produced by a different analyzer). A "FieldIndex" is a reverse
+index able to store such documents, keeping one inverted list per field and term, so
+that a term can be looked up in a single field. The tokens of every field are also
+indexed as the tokens of the whole document, so that the fields can still be searched
+all at once. The fields of a document are laid one after another: the positions of a
+field start "FieldPositionGap" positions after the last position of the previous one,
+so that neither phrases nor "NEAR" queries can match across two fields. The positions
+of the first field are left untouched. Field names are made of lower-case letters,
+digits and underscores. "IterateOverFieldTerms" and "IterateOverFieldDictionary" must
+behave exactly like "IterateOverTerms" and "IterateOverDictionary", but for the terms
+of the given field only. The "Boost" of a field is set at index time and kept by the
+index as part of its schema: "FieldBoost" yields the boost of the matches within the
+field (one, when it has never been set, or when the field is unknown).
+==================================================================================*/
+
+package core
+
+import (
+	"iter"
+)
+
+const FieldPositionGap = 100
+
+type DocumentField struct {
+	Name   string
+	Tokens iter.Seq[Token]
+	Boost  float64
+}
+
+type FieldIndex interface {
+	IterateOverFieldTerms(field string, term string) iter.Seq[TermTracker]
+	IterateOverFieldDictionary(field string, prefix string) iter.Seq[string]
+	StoreNewFieldedDocument(fields []DocumentField) (DocumentId, error)
+	UpsertFieldedDocument(externalId string, fields []DocumentField) (DocumentId, error)
+	FieldBoost(field string) float64
+}
+
+func IterateFieldTokens(fields []DocumentField) iter.Seq2[string, Token] {
+	return func(yield func(string, Token) bool) {
+		offset, next := TermPosition(0), TermPosition(0)
+		for index, field := range fields {
+			if index > 0 {
+				offset = next + FieldPositionGap
+			}
+			for token := range field.Tokens {
+				token.Position += offset
+				next = max(next, token.Position+1)
+				if !yield(field.Name, token) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -14,6 +14,16 @@ a single term and the documents that contain it, togheter with the positions of
 term in the document. Is essential for the well functioning of the search engine
 that terms are iterated in ascending order of document-id and position within a given
 document. The "IterateOverTerms" must herby work in this way in every implementation.
+Similarly, "IterateOverDocuments" must yield every stored document in ascending order
+of document-id, while "IterateOverDictionary" must yield every indexed term starting
+with the given prefix in ascending lexicographical order. The "Boost" of a "Token" is
+only meaningful for tokens found by a query: it scales the contribution of the token
+to the score of the document (zero stands for the default weight, which is one).
+"StartOffset" and "EndOffset" delimit the "OriginalText" of a "Token" in the source
+text it comes from, as byte offsets (the end one being exclusive).
+Once a document has been deleted, it must never be yielded again by any iterator.
+"UpsertDocument" stores a document under an external id (e.g. a file-path), replacing
+the document previously stored under the same external id, if any.
 ==================================================================================*/
 
 package core
@@ -30,6 +40,16 @@ type Token struct {
 	StemmedText string
 	OriginalText string
 	Position    TermPosition
+	Boost       float64
+	StartOffset int
+	EndOffset   int
+}
+
+func (t Token) Weight() float64 {
+	if t.Boost == 0 {
+		return 1
+	}
+	return t.Boost
 }
 
 type TermTracker struct {
@@ -39,5 +59,9 @@ type TermTracker struct {
 
 type ReverseIndex interface {
 	IterateOverTerms(term string) iter.Seq[TermTracker]
+	IterateOverDocuments() iter.Seq[DocumentId]
+	IterateOverDictionary(prefix string) iter.Seq[string]
 	StoreNewDocument(toks iter.Seq[Token]) (DocumentId, error)
+	UpsertDocument(externalId string, toks iter.Seq[Token]) (DocumentId, error)
+	DeleteDocument(docId DocumentId) error
 }
@@ -0,0 +1,29 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+An "NGramIndex" is a reverse index that also keeps, separately from the terms, the
+inverted lists of the character n-grams of the indexed documents (e.g. the trigrams
+"xk-", "k-4", ...), which are needed to find a document by any substring of its
+words. N-grams are stored apart, so that they never match a regular term, nor do they
+affect the statistics of the index. Just like "IterateOverTerms", "IterateOverNGrams"
+must yield the occurrences of an n-gram in ascending order of document-id and position,
+and it must never yield deleted documents. N-grams are extracted from whole
+whitespace-separated words (punctuation included), and the position of an n-gram is
+the position of the first token of the word it has been extracted from.
+==================================================================================*/
+
+package core
+
+import (
+	"iter"
+)
+
+type NGramIndex interface {
+	IterateOverNGrams(nGram string) iter.Seq[TermTracker]
+	StoreDocumentNGrams(docId DocumentId, nGrams iter.Seq[Token]) error
+}
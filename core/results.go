@@ -8,17 +8,24 @@
 
 A "ResultSet" is the way multiple instances of "SearchResult" can be stored and
 iterated over in Quinto. It is designed to be as simple of an interface as possible.
+Every "SearchResult" refers to a single document, and carries the tokens involved
+in every match that has been found in that document. The stored fields of the
+document (if any) are only attached to the final results, since loading them for
+every candidate result would be a waste.
 ==================================================================================*/
 
 package core
 
 import (
 	"iter"
+	"quinto/data"
 )
 
 type SearchResult struct {
-	DocId DocumentId
-	Score float64
+	DocId          DocumentId
+	Score          float64
+	InvolvedTokens data.Set[Token]
+	Document       *StoredDocument
 }
 
 type ResultSet interface {
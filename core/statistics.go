@@ -0,0 +1,22 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+"IndexStatistics" describes a reverse index that is able to provide the collection
+statistics needed by relevance scoring functions (e.g. BM25): how many documents
+have been indexed, how long every document is (in terms of indexed tokens), the
+average length of a document, and how many documents contain a given term.
+==================================================================================*/
+
+package core
+
+type IndexStatistics interface {
+	DocumentsCount() uint64
+	DocumentLength(docId DocumentId) uint64
+	AverageDocumentLength() float64
+	DocumentFrequency(term string) uint64
+}
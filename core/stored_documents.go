@@ -0,0 +1,32 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+A "StoredDocument" holds whatever must be given back to the user about a document,
+which cannot be recovered from the reverse index alone: its original text, together
+with arbitrary key/value metadata (e.g. a title, or the path of the file it has been
+read from). A "DocumentStore" is every entity capable of persisting a "StoredDocument"
+at index time, and of loading it back (e.g. to be displayed along with a search
+result). Loading a document that has never been stored, or that has been deleted,
+must report that the document does not exist. The "Language" of a document is the
+language its text has been analyzed with: "StoredLanguages" must yield every language
+ever stored, so that queries can be analyzed in every language of the collection.
+==================================================================================*/
+
+package core
+
+type StoredDocument struct {
+	Text     string
+	Language string
+	Metadata map[string]string
+}
+
+type DocumentStore interface {
+	StoreDocumentFields(docId DocumentId, document StoredDocument) error
+	LoadDocumentFields(docId DocumentId) (StoredDocument, bool, error)
+	StoredLanguages() []string
+}
@@ -0,0 +1,76 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+This file contains a simple implementation of a bitmap data structure, which is a
+compact set of non-negative integers. Every integer is represented by a single bit
+of a slice of 64-bit words, which grows on demand. It is particularly suited for
+dense sets of small integers (e.g. sequential identifiers). The underlying words can
+be exported and imported, so that the bitmap can be easily serialized.
+==================================================================================*/
+
+package data
+
+import (
+	"iter"
+	"math/bits"
+)
+
+type Bitmap struct {
+	words []uint64
+}
+
+func NewBitmap() *Bitmap {
+	return &Bitmap{words: make([]uint64, 0)}
+}
+
+func NewBitmapFromWords(words []uint64) *Bitmap {
+	return &Bitmap{words: words}
+}
+
+func (b *Bitmap) Insert(value uint64) bool {
+	wordIndex, mask := value/64, uint64(1)<<(value%64)
+	for uint64(len(b.words)) <= wordIndex {
+		b.words = append(b.words, 0)
+	}
+	if b.words[wordIndex]&mask != 0 {
+		return false
+	}
+	b.words[wordIndex] |= mask
+	return true
+}
+
+func (b *Bitmap) Contains(value uint64) bool {
+	wordIndex, mask := value/64, uint64(1)<<(value%64)
+	return wordIndex < uint64(len(b.words)) && b.words[wordIndex]&mask != 0
+}
+
+func (b *Bitmap) Size() int {
+	size := 0
+	for _, word := range b.words {
+		size += bits.OnesCount64(word)
+	}
+	return size
+}
+
+func (b *Bitmap) Words() []uint64 {
+	return b.words
+}
+
+func (b *Bitmap) Iterate() iter.Seq[uint64] {
+	return func(yield func(uint64) bool) {
+		for wordIndex, word := range b.words {
+			for word != 0 {
+				bit := uint64(bits.TrailingZeros64(word))
+				if !yield(uint64(wordIndex)*64 + bit) {
+					return
+				}
+				word &= word - 1
+			}
+		}
+	}
+}
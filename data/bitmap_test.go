@@ -0,0 +1,46 @@
+package data
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBitmapInsertAndContains(t *testing.T) {
+	bitmap := NewBitmap()
+	for _, value := range []uint64{3, 64, 130, 3} {
+		bitmap.Insert(value)
+	}
+
+	if bitmap.Insert(64) {
+		t.Errorf("Expected inserting a duplicate to return false")
+	}
+
+	for _, value := range []uint64{3, 64, 130} {
+		if !bitmap.Contains(value) {
+			t.Errorf("Expected bitmap to contain %d", value)
+		}
+	}
+
+	for _, value := range []uint64{0, 63, 65, 1000} {
+		if bitmap.Contains(value) {
+			t.Errorf("Expected bitmap not to contain %d", value)
+		}
+	}
+
+	if size := bitmap.Size(); size != 3 {
+		t.Errorf("Expected size 3, got %d", size)
+	}
+}
+
+func TestBitmapRoundTripThroughWords(t *testing.T) {
+	bitmap := NewBitmap()
+	for _, value := range []uint64{130, 1, 64} {
+		bitmap.Insert(value)
+	}
+
+	restored := NewBitmapFromWords(slices.Clone(bitmap.Words()))
+	values := CollectAsSlice(restored.Iterate())
+	if !slices.Equal(values, []uint64{1, 64, 130}) {
+		t.Errorf("Expected [1 64 130], got %v", values)
+	}
+}
@@ -9,12 +9,18 @@
 This file contains the implementation of a lockfree concurrent doubly linked list
 implementation. It works because the two links (pointers) are stored in a single
 object, and it's address it's used atomically using the `atomic.Pointer` primitive.
+Links are never modified in place: every update swaps in a new link object.
+Removing a node marks it at once (iterations skip marked nodes), while unlinking it
+from its neighbours is serialized by a mutex, so that two removals never rebind the
+same links concurrently. The head and the tail cannot be unlinked, so they are kept
+aside while marked, and unlinked by a later removal, once they have neighbours.
 ===================================================================================*/
 
 package data
 
 import (
 	"iter"
+	"sync"
 	"sync/atomic"
 )
 
@@ -23,9 +29,8 @@ type ConcurrentList[T any] struct {
 	tail atomic.Pointer[concurrentListNode[T]]
 	size atomic.Int64
 
-	pruningRighNow       atomic.Bool
-	markedForDeletion    chan *concurrentListNode[T]
-	markedNodesThreshold float64
+	pruneMutex  sync.Mutex
+	markedEdges []*concurrentListNode[T]
 }
 
 type concurrentListNode[T any] struct {
@@ -41,7 +46,6 @@ type concurrentListLink[T any] struct {
 
 func NewLinkedList[T any]() *ConcurrentList[T] {
 	emptyList := &ConcurrentList[T]{}
-	emptyList.markedForDeletion = make(chan *concurrentListNode[T])
 	emptyList.head.Store(nil)
 	emptyList.tail.Store(nil)
 	emptyList.size.Store(0)
@@ -58,13 +62,12 @@ func (list *ConcurrentList[T]) InsertFront(value T) ConcurrentListEntry[T] {
 	for {
 		currentHead := list.head.Load()
 		newNode.link.Store(&concurrentListLink[T]{
-			next: list.head.Load(),
+			next: currentHead,
 			prev: nil,
 		})
 		if list.head.CompareAndSwap(currentHead, newNode) {
 			if currentHead != nil {
-				oldHeadLink := currentHead.link.Load()
-				oldHeadLink.prev = newNode
+				list.rebindPrev(currentHead, newNode)
 			} else {
 				list.tail.Store(newNode)
 			}
@@ -129,43 +132,43 @@ func (list *ConcurrentList[T]) rebindAndSkipRight(listNode *concurrentListNode[T
 	}
 }
 
-func (list *ConcurrentList[T]) tryPrune() {
-	toPruneCount := len(list.markedForDeletion)
-	sizeFloat := float64(list.size.Load())
-	countFloat := float64(toPruneCount)
-	if toPruneCount == 0 || countFloat/sizeFloat <= list.markedNodesThreshold {
-		return
-	}
-	if !list.pruningRighNow.CompareAndSwap(false, true) {
-		return
+func (list *ConcurrentList[T]) rebindPrev(listNode *concurrentListNode[T], prev *concurrentListNode[T]) {
+	for {
+		currentLink := listNode.link.Load()
+		newLink := &concurrentListLink[T]{
+			next: currentLink.next,
+			prev: prev,
+		}
+		if listNode.link.CompareAndSwap(currentLink, newLink) {
+			break
+		}
 	}
-	defer list.pruningRighNow.Store(false)
-	markedCount := len(list.markedForDeletion)
-	for range markedCount {
-		toPrune := <-list.markedForDeletion
-		list.removeNode(toPrune)
+}
+
+func (list *ConcurrentList[T]) unlink(listNode *concurrentListNode[T]) bool {
+	link := listNode.link.Load()
+	if link.prev == nil || link.next == nil {
+		return false
 	}
+	list.rebindAndSkipLeft(listNode)
+	list.rebindAndSkipRight(listNode)
+	return true
 }
 
 func (list *ConcurrentList[T]) removeNode(listNode *concurrentListNode[T]) {
-	if listNode == nil {
-		return
-	}
-	if !listNode.mark.Load() {
-		listNode.mark.Store(true)
-		list.size.Add(-1)
-	}
-	link := listNode.link.Load()
-	if link.prev == nil {
-		list.markedForDeletion <- listNode
+	if listNode == nil || !listNode.mark.CompareAndSwap(false, true) {
 		return
 	}
-	if link.next == nil {
-		return
+	list.size.Add(-1)
+	list.pruneMutex.Lock()
+	defer list.pruneMutex.Unlock()
+	stillMarked := list.markedEdges[:0]
+	for _, markedNode := range append(list.markedEdges, listNode) {
+		if !list.unlink(markedNode) {
+			stillMarked = append(stillMarked, markedNode)
+		}
 	}
-	list.rebindAndSkipLeft(listNode)
-	list.rebindAndSkipRight(listNode)
-	list.tryPrune()
+	list.markedEdges = stillMarked
 }
 
 type ConcurrentListEntry[T any] struct {
@@ -17,10 +17,10 @@ func TestConcurrentListInsertions(t *testing.T) {
 	for i := range numItems / 2 {
 		go func() {
 			entry := list.InsertFront("item-" + strconv.Itoa(i))
-			entries[i] = entry
+			entries[2*i] = entry
 			wg.Done()
 			entry = list.InsertFront("item-" + strconv.Itoa(i))
-			entries[2*i] = entry
+			entries[2*i+1] = entry
 			wg.Done()
 		}()
 	}
@@ -190,3 +190,52 @@ func TestConcurrentListInsertionsAndDeletionsAndBackwardsIterations(t *testing.T
 		t.Fatalf("Expected list size %d, got %d", expectedSizeInt, size)
 	}
 }
+
+func TestConcurrentListRemoveHeadAndTail(t *testing.T) {
+	list := NewLinkedList[string]()
+	tail := list.InsertFront("tail")
+	list.InsertFront("middle")
+	head := list.InsertFront("head")
+
+	head.Remove()
+	tail.Remove()
+
+	values := []string{}
+	for entry := range list.IterateForward() {
+		values = append(values, entry.Value())
+	}
+	if len(values) != 1 || values[0] != "middle" {
+		t.Fatalf("Expected only 'middle' to be left, got %v", values)
+	}
+
+	if size := list.Size(); size != 1 {
+		t.Fatalf("Expected list size 1, got %d", size)
+	}
+}
+
+func TestConcurrentListUnlinksRemovedHeads(t *testing.T) {
+	list := NewLinkedList[string]()
+	tail := list.InsertFront("a")
+	list.InsertFront("b")
+	head := list.InsertFront("c")
+
+	head.Remove()
+	list.InsertFront("d")
+	tail.Remove()
+
+	linked := []string{}
+	for cursor := list.head.Load(); cursor != nil; cursor = cursor.link.Load().next {
+		linked = append(linked, cursor.item)
+	}
+	if len(linked) != 3 || linked[0] != "d" || linked[1] != "b" || linked[2] != "a" {
+		t.Fatalf("Expected the removed head to be unlinked, got %v", linked)
+	}
+	if len(list.markedEdges) != 1 || list.markedEdges[0].item != "a" {
+		t.Fatalf("Expected only the removed tail to be kept aside")
+	}
+
+	values := CollectAsSlice(list.IterateBackwards())
+	if len(values) != 2 || values[0].Value() != "b" || values[1].Value() != "d" {
+		t.Fatalf("Expected [b d] iterating backwards")
+	}
+}
@@ -38,6 +38,11 @@ func (m *ConcurrentMap[K, V]) Get(key K) (V, bool) {
 	return value.(V), true
 }
 
+func (m *ConcurrentMap[K, V]) GetOrSet(key K, value V) (V, bool) {
+	actual, loaded := m.storage.LoadOrStore(key, value)
+	return actual.(V), loaded
+}
+
 func (m *ConcurrentMap[K, V]) Delete(key K) {
 	m.storage.Delete(key)
 }
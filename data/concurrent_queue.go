@@ -6,40 +6,56 @@
 
 ============================== BRIEF FILE DESCRIPTION ===============================
 
-This files contains the implementation of a Queue as a golang channel. It is a simple
-wrapper that exposes a Queue API. This wrapper is needed to provide modularity and
-ease of replacement in case in future a lockfree queue implementation is needed.
+This files contains the implementation of an unbounded FIFO Queue protected by a
+mutex. It is a simple wrapper that exposes a Queue API. Pushing never blocks, and
+popping from an empty queue returns immediately with a `false` flag, which makes it
+suitable for producers that must not wait on their consumers (e.g. a background
+worker polling the queue at regular intervals). This wrapper is needed to provide
+modularity and ease of replacement in case in future a lockfree queue is needed.
 ===================================================================================*/
 
 package data
 
 import (
-	"sync/atomic"
+	"sync"
 )
 
 type ConcurrentQueue[T any] struct {
-	channel chan T
-	size    atomic.Int64
+	mutex   sync.Mutex
+	storage []T
 }
 
 func NewConcurrentQueue[T any]() *ConcurrentQueue[T] {
 	return &ConcurrentQueue[T]{
-		channel: make(chan T),
-		size:    atomic.Int64{},
+		storage: make([]T, 0),
 	}
 }
 
 func (q *ConcurrentQueue[T]) Push(value T) {
-	q.size.Add(1)
-	q.channel <- value
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.storage = append(q.storage, value)
 }
 
 func (q *ConcurrentQueue[T]) Pop() (T, bool) {
-	q.size.Add(-1)
-	value, ok := <-q.channel
-	return value, ok
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	var zeroValue T
+	if len(q.storage) == 0 {
+		return zeroValue, false
+	}
+	value := q.storage[0]
+	q.storage[0] = zeroValue
+	q.storage = q.storage[1:]
+	return value, true
+}
+
+func (q *ConcurrentQueue[T]) Size() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.storage)
 }
 
 func (q *ConcurrentQueue[T]) IsEmpty() bool {
-	return q.size.Load() == 0
+	return q.Size() == 0
 }
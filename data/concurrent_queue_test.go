@@ -0,0 +1,51 @@
+package data
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestConcurrentQueueFifoOrder(t *testing.T) {
+	queue := NewConcurrentQueue[int]()
+	if _, ok := queue.Pop(); ok {
+		t.Fatalf("Expected pop from an empty queue to fail")
+	}
+	for i := range 10 {
+		queue.Push(i)
+	}
+	for i := range 10 {
+		value, ok := queue.Pop()
+		if !ok || value != i {
+			t.Fatalf("Expected %d, got %d (ok = %v)", i, value, ok)
+		}
+	}
+	if !queue.IsEmpty() {
+		t.Errorf("Expected queue to be empty")
+	}
+}
+
+func TestConcurrentQueuePushDoesNotBlock(t *testing.T) {
+	const numItems = 1000
+	queue := NewConcurrentQueue[int]()
+	var wg sync.WaitGroup
+	wg.Add(numItems)
+	for i := range numItems {
+		go func() {
+			defer wg.Done()
+			queue.Push(i)
+		}()
+	}
+	wg.Wait()
+
+	if size := queue.Size(); size != numItems {
+		t.Fatalf("Expected queue size %d, got %d", numItems, size)
+	}
+
+	popped := 0
+	for _, ok := queue.Pop(); ok; _, ok = queue.Pop() {
+		popped++
+	}
+	if popped != numItems {
+		t.Errorf("Expected %d pops, got %d", numItems, popped)
+	}
+}
@@ -101,7 +101,7 @@ func (h *Heap[T]) shiftDown(currentIdx int, endIdx int) {
 func (h *Heap[T]) shiftUp() {
 	currentIdx := h.Size() - 1
 	parentIdx := (currentIdx - 1) / 2
-	for h.compareAtIndex(currentIdx, parentIdx) {
+	for currentIdx > 0 && h.compareAtIndex(currentIdx, parentIdx) {
 		h.swap(currentIdx, parentIdx)
 		currentIdx = parentIdx
 		parentIdx = (currentIdx - 1) / 2
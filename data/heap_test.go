@@ -69,3 +69,17 @@ func TestMultipleElementsHeap(t *testing.T) {
 		t.Errorf("Expected heap size to be 0 after Pop, got %d", size)
 	}
 }
+
+func TestHeapWithNonStrictOrderingPredicate(t *testing.T) {
+	heap := NewHeap(func(a, b int) bool { return a <= b })
+
+	for _, value := range []int{5, 3, 3, 8, 1} {
+		heap.Push(value)
+	}
+
+	for _, expected := range []int{1, 3, 3, 5, 8} {
+		if value, exists := heap.Pop(); !exists || value != expected {
+			t.Errorf("Expected Pop to return %d, got %v", expected, value)
+		}
+	}
+}
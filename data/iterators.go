@@ -9,15 +9,20 @@
 This files contains some slice-related utilities that are often needed in the codebase.
 Such utilities are not specific to a particular package, but are used in multiple
 packages. Most of them are simple wrappers/adapters around the "iter" package.
+A "TextSpan" is a whitespace-separated word of some text, together with its byte
+offsets in the text (the end one being exclusive): the text is read as a stream, so
+that the offsets of the words of a file always refer to the beginning of the file.
 ==================================================================================*/
 
 package data
 
 import (
 	"bufio"
+	"io"
 	"iter"
-	"os"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 func ZipIterators[T, U any](firstIterator iter.Seq[T], secondIterator iter.Seq[U]) iter.Seq2[T, U] {
@@ -71,23 +76,53 @@ func NewSliceIterator[T any](slice []T) iter.Seq[T] {
 	}
 }
 
+type TextSpan struct {
+	Text        string
+	StartOffset int
+	EndOffset   int
+}
+
 func NewStringIterator(inlineText string) iter.Seq[string] {
 	return func(yield func(string) bool) {
-		for _, value := range strings.Fields(inlineText) {
-			if !yield(value) {
+		for span := range NewTextSpanIterator(inlineText) {
+			if !yield(span.Text) {
 				break
 			}
 		}
 	}
 }
 
-func NewFileReaderIterator(file *os.File) iter.Seq[string] {
-	return func(yield func(string) bool) {
-		scanner := bufio.NewScanner(file)
-		for scanner.Scan() {
-			if !yield(scanner.Text()) {
-				break
+func NewTextSpanIterator(inlineText string) iter.Seq[TextSpan] {
+	return NewFileReaderIterator(strings.NewReader(inlineText))
+}
+
+func NewFileReaderIterator(reader io.Reader) iter.Seq[TextSpan] {
+	return func(yield func(TextSpan) bool) {
+		buffered := bufio.NewReader(reader)
+		word := []byte{}
+		offset := 0
+		for {
+			r, size, err := buffered.ReadRune()
+			if err != nil || unicode.IsSpace(r) {
+				if len(word) > 0 && !yield(TextSpan{string(word), offset - len(word), offset}) {
+					return
+				}
+				word = word[:0]
+			}
+			if err != nil {
+				return
+			}
+			offset += size
+			if unicode.IsSpace(r) {
+				continue
+			}
+			if r == utf8.RuneError && size == 1 {
+				buffered.UnreadRune()
+				b, _ := buffered.ReadByte()
+				word = append(word, b)
+				continue
 			}
+			word = utf8.AppendRune(word, r)
 		}
 	}
 }
@@ -0,0 +1,41 @@
+package data
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTextSpanIteratorOffsets(t *testing.T) {
+	text := "  héllo\twörld,\n\nnew line "
+	spans := CollectAsSlice(NewTextSpanIterator(text))
+	expected := []string{"héllo", "wörld,", "new", "line"}
+
+	if len(spans) != len(expected) {
+		t.Fatalf("Expected %d spans, got %v", len(expected), spans)
+	}
+
+	for i, span := range spans {
+		if span.Text != expected[i] {
+			t.Errorf("Expected span %d to be %q, got %q", i, expected[i], span.Text)
+		}
+		if text[span.StartOffset:span.EndOffset] != span.Text {
+			t.Errorf("Expected offsets [%d, %d) to delimit %q", span.StartOffset, span.EndOffset, span.Text)
+		}
+	}
+}
+
+func TestFileReaderIteratorKeepsInvalidBytes(t *testing.T) {
+	text := "abc \xffdef ghi"
+	spans := CollectAsSlice(NewFileReaderIterator(strings.NewReader(text)))
+
+	if len(spans) != 3 || spans[1].Text != "\xffdef" || spans[2].StartOffset != 9 {
+		t.Errorf("Expected invalid bytes to be kept as they are, got %v", spans)
+	}
+}
+
+func TestFileReaderIteratorWithVeryLongLines(t *testing.T) {
+	text := strings.Repeat("word ", 100000)
+	if count := CountIterations(NewFileReaderIterator(strings.NewReader(text))); count != 100000 {
+		t.Errorf("Expected 100000 spans, got %d", count)
+	}
+}
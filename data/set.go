@@ -14,6 +14,10 @@ set and the values are dummy values (e.g. booleans that are always true).
 
 package data
 
+import (
+	"iter"
+)
+
 type Set[T comparable] struct {
 	storage map[T]bool
 }
@@ -50,6 +54,10 @@ func (s *Set[T]) InsertAll(other *Set[T]) {
 	}
 }
 
+func (s *Set[T]) Remove(value T) {
+	delete(s.storage, value)
+}
+
 func (s *Set[T]) Contains(value T) bool {
 	flag, exists := s.storage[value]
 	return exists && flag
@@ -58,3 +66,13 @@ func (s *Set[T]) Contains(value T) bool {
 func (s *Set[T]) Size() int {
 	return len(s.storage)
 }
+
+func (s *Set[T]) Iterate() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for value := range s.storage {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
@@ -3,8 +3,10 @@ module quinto
 go 1.24.2
 
 // github.com/spf13/cobra: CLI command/argument parser
+// golang.org/x/text: Unicode normalization forms
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/cobra v1.8.1 // direct
 	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/text v0.28.0 // direct
 )
@@ -8,9 +8,10 @@
 
 V-byte encoding is a variable-length encoding scheme for unsigned integers. It is used
 to compress integers that are likely to be small. Each byte uses the most significant
-bit (MSB) as a continuation flag (The remaining 7 bits store the actual value):
-	- If MSB = 1, more bytes follow.
-	- If MSB = 0, this is the last byte of the integer.
+bit (MSB) as a continuation flag (The remaining 7 bits store the actual value).
+The most significant group of 7 bits is stored first:
+	- If MSB = 0, this is the first byte of the integer.
+	- If MSB = 1, this byte continues the integer started by the previous ones.
 
 For example, the following numbers are encoded as follows:
   00000000 00000000 00000000 00110101 -> [0]0110101
@@ -21,7 +22,9 @@ For example, the following numbers are encoded as follows:
 This encoding uses fewer bytes for small numbers, which is useful for compressing
 the difference between document IDs and positions in an inverted index. Since
 document IDs and positions are stored sequentially, the difference between them
-is likely to be small, and thus the v-byte encoding will be efficient.
+is likely to be small, and thus the v-byte encoding will be efficient. Since the
+end of an integer is only known once the first byte of the next one is read, the
+decoder needs a reader that is able to un-read that byte (an `io.ByteScanner`).
 ==================================================================================*/
 
 package persistence
@@ -56,16 +59,25 @@ func vbyteDecodeUInt64(encoded []byte) uint64 {
 	return decoded
 }
 
-func loadVbyteEncodedUInt64(fileReader io.ByteReader) ([]byte, error) {
-	var encoded []byte
-	encodedByte, err := fileReader.ReadByte()
-	for ; err == nil; encodedByte, err = fileReader.ReadByte() {
-		encoded = append(encoded, encodedByte)
+func loadVbyteEncodedUInt64(fileReader io.ByteScanner) ([]byte, error) {
+	firstByte, err := fileReader.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	encoded := []byte{firstByte}
+	for {
+		encodedByte, err := fileReader.ReadByte()
+		if err == io.EOF {
+			return encoded, nil
+		}
+		if err != nil {
+			return encoded, err
+		}
 		if encodedByte&0x80 == 0 {
-			break
+			return encoded, fileReader.UnreadByte()
 		}
+		encoded = append(encoded, encodedByte)
 	}
-	return encoded, err
 }
 
 func withMSBtoZero(x uint8) uint8 {
@@ -76,3 +76,24 @@ func TestVbyteDecodeUInt64(t *testing.T) {
 		}
 	}
 }
+
+func TestLoadVbyteEncodedUInt64Stream(t *testing.T) {
+	numbers := []uint64{0, 1, 127, 128, 1000, 0xFFFF, 0x80000000, 4800034432111100120}
+	buffer := new(bytes.Buffer)
+	for _, num := range numbers {
+		buffer.Write(vbyteEncodeUInt64(num))
+	}
+	reader := bytes.NewReader(buffer.Bytes())
+	for _, num := range numbers {
+		encoded, err := loadVbyteEncodedUInt64(reader)
+		if err != nil {
+			t.Fatalf("Unexpected error while loading %d: %v", num, err)
+		}
+		if decoded := vbyteDecodeUInt64(encoded); decoded != num {
+			t.Errorf("Expected %d, got %d", num, decoded)
+		}
+	}
+	if _, err := loadVbyteEncodedUInt64(reader); err == nil {
+		t.Errorf("Expected an error once the stream is exhausted")
+	}
+}
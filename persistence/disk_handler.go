@@ -9,9 +9,11 @@
 This file contains the definition of the `diskHandler` interface, which provides a
 layer of abstraction over disk IO. Every disk-resource (files) have a key which
 uniquely identifies it. The `diskHandler` interface allows to retrieve either a
-reader or a writer for a given key. The write operation is temporary and must be
-confiremd by calling the finalize function, which is a callback provided by
-the `getWriter` method as the second return value.
+reader or a writer for a given key. Readers must support un-reading the last byte,
+since the v-byte decoder needs to peek one byte ahead. The write operation is
+temporary and must be confiremd by calling the finalize function, which is a
+callback provided by the `getWriter` method as the second return value: until it
+succeeds, the resource must be considered as not written at all.
 ==================================================================================*/
 
 package persistence
@@ -21,6 +23,6 @@ import (
 )
 
 type diskHandler interface {
-	getWriter(key string) (io.Writer, func(), error)
-	getReader(key string) (io.ByteReader, bool)
+	getWriter(key string) (io.Writer, func() error, error)
+	getReader(key string) (io.ByteScanner, bool)
 }
@@ -0,0 +1,103 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+This file contains the implementation of `FileSystemDiskHandler`, which is the
+concrete `diskHandler` backed by the local file-system. Every key is mapped to a
+single file under a configurable data directory. Keys are escaped so that they can
+be safely used as file names, and overly long keys are shortened using a hash.
+
+Writes are atomic: the writer returned by `getWriter` points to a temporary file
+in the same directory, and the finalize callback flushes it, fsyncs it and then
+renames it over the destination file (any failure is returned by the callback). A
+crash in the middle of a write can never leave a partially written resource behind.
+Resources are small, so readers load a whole file into memory at once: the file is
+closed right away, no matter how much of it the caller ends up reading.
+==================================================================================*/
+
+package persistence
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+const maxFileNameLength = 200
+
+type FileSystemDiskHandler struct {
+	dataDirectory string
+}
+
+func NewFileSystemDiskHandler(dataDirectory string) (*FileSystemDiskHandler, error) {
+	if err := os.MkdirAll(dataDirectory, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileSystemDiskHandler{dataDirectory: dataDirectory}, nil
+}
+
+func (fsh *FileSystemDiskHandler) keyToPath(key string) string {
+	fileName := url.PathEscape(key)
+	if len(fileName) > maxFileNameLength {
+		digest := sha1.Sum([]byte(key))
+		fileName = fileName[:maxFileNameLength-2*len(digest)-1] + "~" + hex.EncodeToString(digest[:])
+	}
+	return filepath.Join(fsh.dataDirectory, fileName)
+}
+
+func (fsh *FileSystemDiskHandler) getWriter(key string) (writer io.Writer, finalize func() error, err error) {
+	tmpFile, err := os.CreateTemp(fsh.dataDirectory, ".tmp-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	buffered := bufio.NewWriter(tmpFile)
+	finalize = func() error {
+		err := commitTemporaryFile(tmpFile, buffered, fsh.keyToPath(key))
+		if err != nil {
+			os.Remove(tmpFile.Name())
+		}
+		return err
+	}
+	return buffered, finalize, nil
+}
+
+func (fsh *FileSystemDiskHandler) getReader(key string) (reader io.ByteScanner, exists bool) {
+	content, err := os.ReadFile(fsh.keyToPath(key))
+	if err != nil {
+		return nil, false
+	}
+	return bytes.NewReader(content), true
+}
+
+func commitTemporaryFile(tmpFile *os.File, buffered *bufio.Writer, destination string) error {
+	flushErr := buffered.Flush()
+	syncErr := tmpFile.Sync()
+	closeErr := tmpFile.Close()
+	for _, err := range []error{flushErr, syncErr, closeErr} {
+		if err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmpFile.Name(), destination); err != nil {
+		return err
+	}
+	return syncDirectory(filepath.Dir(destination))
+}
+
+func syncDirectory(directory string) error {
+	dir, err := os.Open(directory)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
@@ -0,0 +1,179 @@
+package persistence
+
+import (
+	"os"
+	"path/filepath"
+	"quinto/core"
+	"quinto/data"
+	"strings"
+	"testing"
+)
+
+func TestFileSystemDiskHandlerForWritingAndReadingTermTrackers(t *testing.T) {
+	handler, err := NewFileSystemDiskHandler(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create disk handler: %v", err)
+	}
+
+	writer, finalize, err := handler.getWriter("term-hello")
+	if err != nil {
+		t.Fatalf("Failed to get writer: %v", err)
+	}
+
+	inputs := []core.TermTracker{
+		{DocId: 17, Position: 1},
+		{DocId: 17, Position: 2},
+		{DocId: 27, Position: 1},
+		{DocId: 37, Position: 1},
+	}
+
+	encodeStringToDisk(writer, "term-hello")
+	encodeTermTrackersToDisk(writer, data.NewSliceIterator(inputs))
+	finalize()
+
+	reader, exists := handler.getReader("term-hello")
+	if !exists || reader == nil {
+		t.Fatalf("Expected reader to exist after writing")
+	}
+
+	if key, _ := decodeStringFromDisk(reader); key != "term-hello" {
+		t.Errorf("Expected key 'term-hello', got '%s'", key)
+	}
+
+	outputs := data.CollectAsSlice(iterateTermTrackersFromDisk(reader))
+	if len(outputs) != len(inputs) {
+		t.Fatalf("Expected %d term trackers, got %d", len(inputs), len(outputs))
+	}
+
+	for in, out := range data.ZipSlices(inputs, outputs) {
+		if in != out {
+			t.Errorf("Expected term tracker be %v, got %v", in, out)
+		}
+	}
+}
+
+func TestFileSystemDiskHandlerMissingKey(t *testing.T) {
+	handler, _ := NewFileSystemDiskHandler(t.TempDir())
+	if _, exists := handler.getReader("term-missing"); exists {
+		t.Errorf("Expected reader not to exist for a key that was never written")
+	}
+}
+
+func TestFileSystemDiskHandlerWriteIsNotVisibleBeforeFinalize(t *testing.T) {
+	directory := t.TempDir()
+	handler, _ := NewFileSystemDiskHandler(directory)
+
+	writer, finalize, _ := handler.getWriter("term-pending")
+	encodeStringToDisk(writer, "not yet visible")
+
+	if _, exists := handler.getReader("term-pending"); exists {
+		t.Errorf("Expected resource to be invisible before finalize is called")
+	}
+
+	finalize()
+
+	if _, exists := handler.getReader("term-pending"); !exists {
+		t.Errorf("Expected resource to be visible after finalize is called")
+	}
+
+	entries, _ := os.ReadDir(directory)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".tmp-") {
+			t.Errorf("Expected no temporary file left behind, found %s", entry.Name())
+		}
+	}
+}
+
+func TestFileSystemDiskHandlerOverwrite(t *testing.T) {
+	handler, _ := NewFileSystemDiskHandler(t.TempDir())
+	for _, text := range []string{"first", "second"} {
+		writer, finalize, _ := handler.getWriter("meta")
+		encodeStringToDisk(writer, text)
+		finalize()
+	}
+	reader, _ := handler.getReader("meta")
+	if text, _ := decodeStringFromDisk(reader); text != "second" {
+		t.Errorf("Expected 'second', got '%s'", text)
+	}
+}
+
+func TestFileSystemDiskHandlerUnsafeKeys(t *testing.T) {
+	directory := t.TempDir()
+	handler, _ := NewFileSystemDiskHandler(directory)
+	keys := []string{"term-../escape", "term-a/b", strings.Repeat("x", 1000)}
+	for _, key := range keys {
+		writer, finalize, _ := handler.getWriter(key)
+		encodeStringToDisk(writer, key)
+		finalize()
+		reader, exists := handler.getReader(key)
+		if !exists {
+			t.Fatalf("Expected reader to exist for key %q", key)
+		}
+		if text, _ := decodeStringFromDisk(reader); text != key {
+			t.Errorf("Expected %q, got %q", key, text)
+		}
+	}
+	entries, _ := os.ReadDir(filepath.Dir(directory))
+	for _, entry := range entries {
+		if entry.Name() == "escape" {
+			t.Errorf("Expected keys to never escape the data directory")
+		}
+	}
+}
+
+func TestIndexChunkWriteAndReadOnFileSystem(t *testing.T) {
+	termTrackers := []core.TermTracker{
+		{DocId: 17, Position: 3},
+		{DocId: 17, Position: 4},
+		{DocId: 27, Position: 1},
+	}
+
+	handler, _ := NewFileSystemDiskHandler(t.TempDir())
+	writerChunk := newIndexChunk("term-guitar", handler)
+	writerChunk.insertIterable(data.NewSliceIterator(termTrackers))
+	writerChunk.writeBack()
+
+	readerChunk := newIndexChunk("term-guitar", handler)
+	if readerChunk.termTrackers.Size() != len(termTrackers) {
+		t.Errorf("Expected termTrackers size to be %d, got %d", len(termTrackers), readerChunk.termTrackers.Size())
+	}
+}
+
+func TestFileSystemDiskHandlerPartialReadsReleaseFiles(t *testing.T) {
+	descriptors, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("Open file descriptors cannot be listed on this platform")
+	}
+	handler, _ := NewFileSystemDiskHandler(t.TempDir())
+	writer, finalize, _ := handler.getWriter("meta")
+	encodeStringToDisk(writer, "first")
+	encodeStringToDisk(writer, "second")
+	if err := finalize(); err != nil {
+		t.Fatalf("Failed to finalize: %v", err)
+	}
+
+	for range 100 {
+		reader, _ := handler.getReader("meta")
+		if value, _ := decodeStringFromDisk(reader); value != "first" {
+			t.Fatalf("Expected 'first', got '%s'", value)
+		}
+	}
+
+	if after, _ := os.ReadDir("/proc/self/fd"); len(after) > len(descriptors) {
+		t.Errorf("Expected partial reads not to leak files, got %d more open descriptors", len(after)-len(descriptors))
+	}
+}
+
+func TestFileSystemDiskHandlerFinalizeReturnsErrors(t *testing.T) {
+	directory := filepath.Join(t.TempDir(), "index")
+	handler, _ := NewFileSystemDiskHandler(directory)
+	writer, finalize, _ := handler.getWriter("meta")
+	encodeStringToDisk(writer, "value")
+	if err := os.RemoveAll(directory); err != nil {
+		t.Fatalf("Failed to remove the data directory: %v", err)
+	}
+
+	if err := finalize(); err == nil {
+		t.Errorf("Expected an error when the resource cannot be committed")
+	}
+}
@@ -3,9 +3,11 @@ package persistence
 import (
 	"bytes"
 	"io"
+	"sync"
 )
 
 type mockDiskHandler struct {
+	mutex       sync.Mutex
 	mainBuffers map[string]*bytes.Buffer
 }
 
@@ -15,19 +17,23 @@ func newMockDiskHandler() *mockDiskHandler {
 	}
 }
 
-func (m *mockDiskHandler) getWriter(key string) (writer io.Writer, finalize func(), err error) {
+func (m *mockDiskHandler) getWriter(key string) (writer io.Writer, finalize func() error, err error) {
 	tmpBuffer := new(bytes.Buffer)
-	finalize = func() {
+	finalize = func() error {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
 		m.mainBuffers[key] = tmpBuffer
+		return nil
 	}
 	return tmpBuffer, finalize, nil
 }
 
-func (m *mockDiskHandler) getReader(key string) (reader io.ByteReader, exists bool) {
+func (m *mockDiskHandler) getReader(key string) (reader io.ByteScanner, exists bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	mainBuffer, ok := m.mainBuffers[key]
 	if !ok {
-		m.mainBuffers[key] = new(bytes.Buffer)
-		mainBuffer = m.mainBuffers[key]
+		return bytes.NewReader(nil), false
 	}
-	return bytes.NewReader(mainBuffer.Bytes()), ok
+	return bytes.NewReader(mainBuffer.Bytes()), true
 }
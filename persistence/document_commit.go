@@ -0,0 +1,144 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+This file contains the document commit of the `PersistenceManager`: the single
+resource which tells which documents are part of the index. Indexing a document (or
+replacing one, or deleting one) touches many chunks, which are written back one at
+a time, so a crash could leave on disk any mix of old and new chunks. The commit is
+what makes these updates atomic: it holds the highest document-id whose postings
+are completely persisted (the published counter), the number of live documents and
+the sum of their lengths, the tombstones and the external ids, and it is written by a single finalize once every chunk that was dirty when it
+was taken has been written back. Until then, the previous commit stays in place.
+
+Document-ids must never be reused, even when the documents they were handed out to
+never got committed, since their trackers might already be on disk. So the document
+counter is persisted on its own as a reservation, which is written (only when it has
+grown) before writing any chunk. When the index is opened, every document-id above
+the published counter, up to the reserved one, belongs to a document which was
+being stored (or upserted) when the index was last closed abruptly: those documents
+are tombstoned, so that an interrupted upsert leaves the old version in place (and
+they are not counted, since the counters come from the commit as well), and the
+commit is written again at the next write-back. Updates hold the updates lock
+for their whole duration, so a commit is taken while holding it exclusively: it
+never contains a half-indexed document, nor a document which is still hidden.
+==================================================================================*/
+
+package persistence
+
+import (
+	"fmt"
+	"quinto/core"
+	"quinto/data"
+	"strconv"
+)
+
+const documentCommitKey = "meta-document-commit"
+
+type documentCommit struct {
+	documentCounter uint64
+	documentsCount  uint64
+	totalLength     uint64
+	deleted         *data.Bitmap
+	externalIds     map[string]core.DocumentId
+}
+
+func (pm *PersistenceManager) loadDocumentCommit() {
+	reservedCounter := pm.loadDocumentCounter()
+	commit := documentCommit{
+		deleted:     data.NewBitmap(),
+		externalIds: make(map[string]core.DocumentId),
+	}
+	if reader, exists := pm.config.IoHandler.getReader(documentCommitKey); exists && reader != nil {
+		errors := [3]error{}
+		var counterString, documentsCountString, totalLengthString string
+		counterString, errors[0] = decodeStringFromDisk(reader)
+		documentsCountString, errors[1] = decodeStringFromDisk(reader)
+		totalLengthString, errors[2] = decodeStringFromDisk(reader)
+		panicWhenSomeErrorsOccurred(errors[:])
+		commit.documentCounter, _ = strconv.ParseUint(counterString, 10, 64)
+		commit.documentsCount, _ = strconv.ParseUint(documentsCountString, 10, 64)
+		commit.totalLength, _ = strconv.ParseUint(totalLengthString, 10, 64)
+		commit.deleted = decodeTombstones(reader)
+		commit.externalIds = decodeExternalIds(reader)
+	}
+	for docId := commit.documentCounter + 1; docId <= reservedCounter; docId++ {
+		commit.deleted.Insert(docId)
+		pm.commitPending.Store(true)
+	}
+	pm.reservedCounter = max(reservedCounter, commit.documentCounter)
+	pm.documentCounter.Store(pm.reservedCounter)
+	pm.documentsCount.Store(commit.documentsCount)
+	pm.totalLength.Store(commit.totalLength)
+	pm.deleted.bitmap = commit.deleted
+	pm.deleted.hidden = data.NewSet[core.DocumentId]()
+	pm.externalIds.reset(commit.externalIds)
+}
+
+func (pm *PersistenceManager) snapshotDocumentCommit() (documentCommit, int) {
+	pm.updatesMutex.Lock()
+	defer pm.updatesMutex.Unlock()
+	return documentCommit{
+		documentCounter: pm.documentCounter.Load(),
+		documentsCount:  pm.documentsCount.Load(),
+		totalLength:     pm.totalLength.Load(),
+		deleted:         pm.deleted.snapshot(),
+		externalIds:     pm.externalIds.snapshot(),
+	}, pm.pendingSync.Size()
+}
+
+func (pm *PersistenceManager) storeDocumentCommit(commit documentCommit) error {
+	writer, finalize, err := pm.config.IoHandler.getWriter(documentCommitKey)
+	if err != nil {
+		return err
+	}
+	counters := []uint64{commit.documentCounter, commit.documentsCount, commit.totalLength}
+	for _, counter := range counters {
+		if err := encodeStringToDisk(writer, fmt.Sprint(counter)); err != nil {
+			return err
+		}
+	}
+	if err := encodeTombstones(writer, commit.deleted); err != nil {
+		return err
+	}
+	if err := encodeExternalIds(writer, commit.externalIds); err != nil {
+		return err
+	}
+	return finalize()
+}
+
+func (pm *PersistenceManager) loadDocumentCounter() uint64 {
+	reader, exists := pm.config.IoHandler.getReader(documentCounterKey)
+	if !exists || reader == nil {
+		return 0
+	}
+	counterString, err := decodeStringFromDisk(reader)
+	panicWhenSomeErrorsOccurred([]error{err})
+	counter, _ := strconv.ParseUint(counterString, 10, 64)
+	return counter
+}
+
+func (pm *PersistenceManager) reserveDocumentIds() error {
+	pm.reservationMutex.Lock()
+	defer pm.reservationMutex.Unlock()
+	counter := pm.documentCounter.Load()
+	if counter <= pm.reservedCounter {
+		return nil
+	}
+	writer, finalize, err := pm.config.IoHandler.getWriter(documentCounterKey)
+	if err != nil {
+		return err
+	}
+	if err := encodeStringToDisk(writer, fmt.Sprint(counter)); err != nil {
+		return err
+	}
+	if err := finalize(); err != nil {
+		return err
+	}
+	pm.reservedCounter = counter
+	return nil
+}
@@ -11,19 +11,24 @@ This file contains the functions that are used to store and load data from disk.
 API is designed around the `persistence.diskHandler` interface, which provides a
 layer of abstraction over the disk operations. This turns out to be useful for
 testing purposes, as it allows us to mock the disk operations and test the
-persistence layer without actually writing to disk.
+persistence layer without actually writing to disk. Strings are prefixed by their
+length, which is encoded as an unsigned varint (the high bit of every byte but the
+last one is set): unlike the v-byte encoding of the term trackers, the end of the
+length is known without peeking at the following byte, which might just as well be
+the first byte of a non-ASCII string.
 ==================================================================================*/
 
 package persistence
 
 import (
+	"encoding/binary"
 	"io"
 	"iter"
 	"quinto/core"
 )
 
 func encodeStringToDisk(fileWriter io.Writer, text string) error {
-	encodedLen := vbyteEncodeUInt64(uint64(len(text)))
+	encodedLen := binary.AppendUvarint(nil, uint64(len(text)))
 	if _, err := fileWriter.Write(encodedLen); err != nil {
 		return err
 	}
@@ -62,9 +67,8 @@ func encodeTermTrackersToDisk(fileWriter io.Writer, invertedListIterator iter.Se
 	return nil
 }
 
-func decodeStringFromDisk(fileReader io.ByteReader) (string, error) {
-	encodedLen, err := loadVbyteEncodedUInt64(fileReader)
-	decodedLen := vbyteDecodeUInt64(encodedLen)
+func decodeStringFromDisk(fileReader io.ByteScanner) (string, error) {
+	decodedLen, err := binary.ReadUvarint(fileReader)
 	if err != nil || decodedLen == 0 {
 		return "", err
 	}
@@ -78,7 +82,7 @@ func decodeStringFromDisk(fileReader io.ByteReader) (string, error) {
 	return string(bytes), nil
 }
 
-func processTermTrackersFromDisk(fileReader io.ByteReader, yield func(core.TermTracker) bool) error {
+func processTermTrackersFromDisk(fileReader io.ByteScanner, yield func(core.TermTracker) bool) error {
 
 	documentId := core.DocumentId(0)
 	position := core.TermPosition(0)
@@ -118,7 +122,7 @@ func processTermTrackersFromDisk(fileReader io.ByteReader, yield func(core.TermT
 	}
 }
 
-func iterateTermTrackersFromDisk(fileReader io.ByteReader) iter.Seq[core.TermTracker] {
+func iterateTermTrackersFromDisk(fileReader io.ByteScanner) iter.Seq[core.TermTracker] {
 	return func(yield func(core.TermTracker) bool) {
 		processTermTrackersFromDisk(fileReader, yield)
 	}
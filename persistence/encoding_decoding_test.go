@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"quinto/core"
 	"quinto/data"
+	"strings"
 	"testing"
 )
 
@@ -119,3 +120,23 @@ func TestEncodeDecodeString(t *testing.T) {
 		}
 	}
 }
+
+func TestEncodeDecodeConsecutiveStrings(t *testing.T) {
+	samples := []string{"Élan vital", "日本", "", strings.Repeat("ü", 200), "hello"}
+	buffer := new(bytes.Buffer)
+	for _, sample := range samples {
+		if err := encodeStringToDisk(buffer, sample); err != nil {
+			t.Fatalf("Failed to encode string: %v", err)
+		}
+	}
+	reader := bytes.NewReader(buffer.Bytes())
+	for _, sample := range samples {
+		result, err := decodeStringFromDisk(reader)
+		if err != nil {
+			t.Fatalf("Failed to decode string: %v", err)
+		}
+		if result != sample {
+			t.Errorf("Expected '%s', got '%s'", sample, result)
+		}
+	}
+}
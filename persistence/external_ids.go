@@ -0,0 +1,160 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+This file contains the mapping between external ids and internal document-ids of
+the `PersistenceManager`. Documents usually come with their own string keys (e.g.
+file-paths, or primary keys of some database), while the index only knows about the
+sequential document-ids it hands out. The mapping is kept in memory in both
+directions, and it is persisted as part of the document commit, encoded as the
+number of entries followed by the entries themselves (sorted by external id).
+
+`UpsertDocument` relies on the mapping to replace the old version of a document:
+the new version is indexed under a fresh document-id while being hidden from
+iterations, then the old version is tombstoned and the new one is revealed at once,
+so that queries never observe both versions (nor none of them). The same holds on
+disk: the binding, the tombstone of the old version and the new document-id are all
+published by the same commit, so a crash in the middle of an upsert leaves the old
+version in place.
+==================================================================================*/
+
+package persistence
+
+import (
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"quinto/core"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+type externalIds struct {
+	mutex      sync.RWMutex
+	toInternal map[string]core.DocumentId
+	toExternal map[core.DocumentId]string
+}
+
+func (ids *externalIds) lookup(externalId string) (core.DocumentId, bool) {
+	ids.mutex.RLock()
+	defer ids.mutex.RUnlock()
+	docId, exists := ids.toInternal[externalId]
+	return docId, exists
+}
+
+func (ids *externalIds) reverseLookup(docId core.DocumentId) (string, bool) {
+	ids.mutex.RLock()
+	defer ids.mutex.RUnlock()
+	externalId, exists := ids.toExternal[docId]
+	return externalId, exists
+}
+
+func (ids *externalIds) bind(externalId string, docId core.DocumentId) {
+	ids.mutex.Lock()
+	defer ids.mutex.Unlock()
+	if oldDocId, exists := ids.toInternal[externalId]; exists {
+		delete(ids.toExternal, oldDocId)
+	}
+	ids.toInternal[externalId] = docId
+	ids.toExternal[docId] = externalId
+}
+
+func (ids *externalIds) unbindDocument(docId core.DocumentId) bool {
+	ids.mutex.Lock()
+	defer ids.mutex.Unlock()
+	externalId, exists := ids.toExternal[docId]
+	if exists {
+		delete(ids.toExternal, docId)
+		delete(ids.toInternal, externalId)
+	}
+	return exists
+}
+
+func (ids *externalIds) snapshot() map[string]core.DocumentId {
+	ids.mutex.RLock()
+	defer ids.mutex.RUnlock()
+	return maps.Clone(ids.toInternal)
+}
+
+func (ids *externalIds) reset(bindings map[string]core.DocumentId) {
+	ids.mutex.Lock()
+	defer ids.mutex.Unlock()
+	ids.toInternal = bindings
+	ids.toExternal = make(map[core.DocumentId]string, len(bindings))
+	for externalId, docId := range bindings {
+		ids.toExternal[docId] = externalId
+	}
+}
+
+func decodeExternalIds(reader io.ByteScanner) map[string]core.DocumentId {
+	countString, err := decodeStringFromDisk(reader)
+	panicWhenSomeErrorsOccurred([]error{err})
+	count, _ := strconv.Atoi(countString)
+	bindings := make(map[string]core.DocumentId, count)
+	for range count {
+		errors := [2]error{}
+		var externalId, docIdString string
+		externalId, errors[0] = decodeStringFromDisk(reader)
+		docIdString, errors[1] = decodeStringFromDisk(reader)
+		panicWhenSomeErrorsOccurred(errors[:])
+		docId, _ := strconv.ParseUint(docIdString, 10, 64)
+		bindings[externalId] = core.DocumentId(docId)
+	}
+	return bindings
+}
+
+func encodeExternalIds(writer io.Writer, bindings map[string]core.DocumentId) error {
+	if err := encodeStringToDisk(writer, fmt.Sprint(len(bindings))); err != nil {
+		return err
+	}
+	for _, externalId := range slices.Sorted(maps.Keys(bindings)) {
+		if err := encodeStringToDisk(writer, externalId); err != nil {
+			return err
+		}
+		if err := encodeStringToDisk(writer, fmt.Sprint(bindings[externalId])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pm *PersistenceManager) UpsertDocument(externalId string, toks iter.Seq[core.Token]) (core.DocumentId, error) {
+	return pm.upsert(externalId, func(docId core.DocumentId) error {
+		pm.indexDocument(docId, toks)
+		return nil
+	})
+}
+
+func (pm *PersistenceManager) upsert(externalId string, index func(docId core.DocumentId) error) (core.DocumentId, error) {
+	pm.upsertMutex.Lock()
+	defer pm.upsertMutex.Unlock()
+	pm.updatesMutex.RLock()
+	defer pm.updatesMutex.RUnlock()
+	oldDocId, _ := pm.externalIds.lookup(externalId)
+	newDocId := core.DocumentId(pm.documentCounter.Add(1))
+	pm.deleted.hide(newDocId)
+	if err := index(newDocId); err != nil {
+		pm.discardDocument(newDocId)
+		return 0, err
+	}
+	pm.externalIds.bind(externalId, newDocId)
+	pm.commitPending.Store(true)
+	if pm.deleted.replace(oldDocId, newDocId) {
+		pm.forgetDocument(oldDocId)
+	}
+	return newDocId, nil
+}
+
+func (pm *PersistenceManager) LookupExternalId(externalId string) (core.DocumentId, bool) {
+	return pm.externalIds.lookup(externalId)
+}
+
+func (pm *PersistenceManager) ExternalId(docId core.DocumentId) (string, bool) {
+	return pm.externalIds.reverseLookup(docId)
+}
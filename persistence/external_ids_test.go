@@ -0,0 +1,265 @@
+package persistence
+
+import (
+	"bytes"
+	"quinto/core"
+	"quinto/data"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpsertDocumentReplacesPreviousVersion(t *testing.T) {
+	manager := NewPersistenceManager(PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       newMockDiskHandler(),
+	})
+	defer manager.Close()
+
+	oldId, _ := manager.UpsertDocument("notes.txt", data.NewSliceIterator(UtilTokensFromWords("guitar", "music")))
+	otherId, _ := manager.UpsertDocument("other.txt", data.NewSliceIterator(UtilTokensFromWords("guitar")))
+	newId, _ := manager.UpsertDocument("notes.txt", data.NewSliceIterator(UtilTokensFromWords("drums", "music", "band")))
+
+	if newId == oldId {
+		t.Fatalf("Expected the new version to get a fresh document-id")
+	}
+
+	if docId, _ := manager.LookupExternalId("notes.txt"); docId != newId {
+		t.Errorf("Expected notes.txt to map to %d, got %d", newId, docId)
+	}
+
+	if externalId, exists := manager.ExternalId(oldId); exists {
+		t.Errorf("Expected the old version not to be mapped anymore, got %s", externalId)
+	}
+
+	guitars := data.CollectAsSlice(manager.IterateOverTerms("guitar"))
+	if len(guitars) != 1 || guitars[0].DocId != otherId {
+		t.Errorf("Expected only the guitar of other.txt, got %v", guitars)
+	}
+
+	musics := data.CollectAsSlice(manager.IterateOverTerms("music"))
+	if len(musics) != 1 || musics[0].DocId != newId {
+		t.Errorf("Expected only the music of the new version, got %v", musics)
+	}
+
+	if count := manager.DocumentsCount(); count != 2 {
+		t.Errorf("Expected 2 documents, got %d", count)
+	}
+}
+
+func TestUpsertDocumentNeverExposesTwoVersions(t *testing.T) {
+	manager := NewPersistenceManager(PersistenceConfig{
+		MaxCachedChunks: 100,
+		MaxChunkSize:    1024,
+		IoHandler:       newMockDiskHandler(),
+	})
+	defer manager.Close()
+
+	manager.UpsertDocument("notes.txt", data.NewSliceIterator(UtilTokensFromWords("guitar")))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 200 {
+			manager.UpsertDocument("notes.txt", data.NewSliceIterator(UtilTokensFromWords("guitar")))
+		}
+	}()
+
+	for range 200 {
+		if iters := data.CountIterations(manager.IterateOverDocuments()); iters != 1 {
+			t.Fatalf("Expected exactly one visible version, got %d", iters)
+		}
+	}
+	wg.Wait()
+}
+
+func TestExternalIdsSurviveRestart(t *testing.T) {
+	handler, err := NewFileSystemDiskHandler(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create disk handler: %v", err)
+	}
+	config := PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       handler,
+	}
+
+	firstManager := NewPersistenceManager(config)
+	firstId, _ := firstManager.UpsertDocument("/docs/a.txt", data.NewSliceIterator(UtilTokensFromWords("guitar")))
+	if err := firstManager.Close(); err != nil {
+		t.Fatalf("Failed to close persistence manager: %v", err)
+	}
+
+	secondManager := NewPersistenceManager(config)
+	defer secondManager.Close()
+
+	if docId, exists := secondManager.LookupExternalId("/docs/a.txt"); !exists || docId != firstId {
+		t.Fatalf("Expected /docs/a.txt to map to %d, got %d", firstId, docId)
+	}
+
+	secondId, _ := secondManager.UpsertDocument("/docs/a.txt", data.NewSliceIterator(UtilTokensFromWords("drums")))
+	if documents := data.CollectAsSlice(secondManager.IterateOverDocuments()); len(documents) != 1 || documents[0] != secondId {
+		t.Errorf("Expected only document %d to be visible, got %v", secondId, documents)
+	}
+
+	secondManager.DeleteDocument(secondId)
+	if _, exists := secondManager.LookupExternalId("/docs/a.txt"); exists {
+		t.Errorf("Expected deleting a document to drop its external id")
+	}
+}
+
+func UtilCrashedCopy(handler *mockDiskHandler) *mockDiskHandler {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	crashed := newMockDiskHandler()
+	for key, buffer := range handler.mainBuffers {
+		crashed.mainBuffers[key] = bytes.NewBuffer(bytes.Clone(buffer.Bytes()))
+	}
+	return crashed
+}
+
+func TestInterruptedUpsertKeepsPreviousVersion(t *testing.T) {
+	handler := newMockDiskHandler()
+	config := PersistenceConfig{
+		MaxCachedChunks:   100,
+		MaxChunkSize:      1024,
+		WriteBackInterval: time.Hour,
+		IoHandler:         handler,
+	}
+	manager := NewPersistenceManager(config)
+	defer manager.Close()
+
+	oldId, _ := manager.UpsertDocument("notes.txt", data.NewSliceIterator(UtilTokensFromWords("guitar")))
+	if err := manager.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	newId, _ := manager.UpsertDocument("notes.txt", data.NewSliceIterator(UtilTokensFromWords("drums", "band")))
+	if err := manager.writeBackBatch(1); err != nil {
+		t.Fatalf("Failed to write back a batch: %v", err)
+	}
+
+	config.IoHandler = UtilCrashedCopy(handler)
+	reopened := NewPersistenceManager(config)
+	defer reopened.Close()
+
+	if docId, _ := reopened.LookupExternalId("notes.txt"); docId != oldId {
+		t.Errorf("Expected notes.txt to still map to %d, got %d", oldId, docId)
+	}
+	if documents := data.CollectAsSlice(reopened.IterateOverDocuments()); len(documents) != 1 || documents[0] != oldId {
+		t.Errorf("Expected only the previous version to be visible, got %v", documents)
+	}
+	for _, term := range []string{"drums", "band"} {
+		if iters := data.CountIterations(reopened.IterateOverTerms(term)); iters != 0 {
+			t.Errorf("Expected no %s tracker of the interrupted upsert, got %d", term, iters)
+		}
+	}
+	if docId, _ := reopened.UpsertDocument("notes.txt", data.NewSliceIterator(UtilTokensFromWords("piano"))); docId <= newId {
+		t.Errorf("Expected a document-id greater than %d, got %d", newId, docId)
+	}
+}
+
+func TestCommittedUpsertSurvivesCrash(t *testing.T) {
+	handler := newMockDiskHandler()
+	config := PersistenceConfig{
+		MaxCachedChunks:   100,
+		MaxChunkSize:      1024,
+		WriteBackInterval: time.Hour,
+		IoHandler:         handler,
+	}
+	manager := NewPersistenceManager(config)
+	defer manager.Close()
+
+	manager.UpsertDocument("notes.txt", data.NewSliceIterator(UtilTokensFromWords("guitar")))
+	newId, _ := manager.UpsertDocument("notes.txt", data.NewSliceIterator(UtilTokensFromWords("drums")))
+	for manager.pendingSync.Size() > 0 {
+		if err := manager.writeBackBatch(1); err != nil {
+			t.Fatalf("Failed to write back a batch: %v", err)
+		}
+	}
+
+	config.IoHandler = UtilCrashedCopy(handler)
+	reopened := NewPersistenceManager(config)
+	defer reopened.Close()
+
+	if documents := data.CollectAsSlice(reopened.IterateOverDocuments()); len(documents) != 1 || documents[0] != newId {
+		t.Errorf("Expected only the new version to be visible, got %v", documents)
+	}
+	if iters := data.CountIterations(reopened.IterateOverTerms("guitar")); iters != 0 {
+		t.Errorf("Expected the previous version to be deleted, got %d guitar trackers", iters)
+	}
+}
+
+func TestInterruptedStoreIsNotCounted(t *testing.T) {
+	handler := newMockDiskHandler()
+	config := PersistenceConfig{
+		MaxCachedChunks:   100,
+		MaxChunkSize:      1024,
+		WriteBackInterval: time.Hour,
+		IoHandler:         handler,
+	}
+	manager := NewPersistenceManager(config)
+	defer manager.Close()
+
+	manager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("guitar", "music")))
+	if err := manager.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	manager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("drums", "band", "music", "rock")))
+	if err := manager.writeBackBatch(1); err != nil {
+		t.Fatalf("Failed to write back a batch: %v", err)
+	}
+
+	config.IoHandler = UtilCrashedCopy(handler)
+	reopened := NewPersistenceManager(config)
+	defer reopened.Close()
+
+	if count := reopened.DocumentsCount(); count != 1 {
+		t.Errorf("Expected the interrupted document not to be counted, got %d documents", count)
+	}
+	if average := reopened.AverageDocumentLength(); average != 2 {
+		t.Errorf("Expected an average length of 2, got %f", average)
+	}
+}
+
+func TestIndexDocumentPublishesStoredFieldsAndNGrams(t *testing.T) {
+	handler := newMockDiskHandler()
+	config := PersistenceConfig{
+		MaxCachedChunks:   100,
+		MaxChunkSize:      1024,
+		WriteBackInterval: time.Hour,
+		IoHandler:         handler,
+	}
+	manager := NewPersistenceManager(config)
+	defer manager.Close()
+
+	docId, err := manager.IndexDocument(core.IndexedDocument{
+		ExternalId: "notes.txt",
+		Tokens:     data.NewSliceIterator(UtilTokensFromWords("guitar")),
+		NGrams:     data.NewSliceIterator(UtilTokensFromWords("gui", "uit", "ita", "tar")),
+		Stored:     core.StoredDocument{Text: "Guitar", Language: "eng"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to index document: %v", err)
+	}
+	for manager.pendingSync.Size() > 0 {
+		if err := manager.writeBackBatch(1); err != nil {
+			t.Fatalf("Failed to write back a batch: %v", err)
+		}
+	}
+
+	config.IoHandler = UtilCrashedCopy(handler)
+	reopened := NewPersistenceManager(config)
+	defer reopened.Close()
+
+	if documents := data.CollectAsSlice(reopened.IterateOverDocuments()); len(documents) != 1 || documents[0] != docId {
+		t.Fatalf("Expected document %d to be published, got %v", docId, documents)
+	}
+	if document, exists, _ := reopened.LoadDocumentFields(docId); !exists || document.Text != "Guitar" {
+		t.Errorf("Expected the stored text of the published document, got %v %q", exists, document.Text)
+	}
+	if iters := data.CountIterations(reopened.IterateOverNGrams("ita")); iters != 1 {
+		t.Errorf("Expected 1 ita n-gram of the published document, got %d", iters)
+	}
+}
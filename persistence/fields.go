@@ -0,0 +1,181 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+This file contains the field index of the `PersistenceManager`. The inverted list of
+a term within a field is a chain of index chunks, exactly like the one of a term
+within the whole document, but it lives in its own keyspace ("field-<field>-<term>"):
+since field names cannot contain dashes, the keys of two different fields never
+collide. A fielded document is indexed twice: as a whole, under the regular term
+keys (which accounts for its length and for the term dictionary), and field by field,
+under the field keys. Every (field, term) pair is recorded in a dictionary of its own
+(as "<field>-<term>"), which is needed to expand prefix, wildcard and fuzzy queries
+within a field, and to compact the field chains once some documents are deleted.
+The boosts of the fields (the schema of the index) are persisted as a single
+resource, made of the number of fields followed by the (field, boost) pairs: the
+boost of a field is the one it has been given the last time it has been indexed.
+==================================================================================*/
+
+package persistence
+
+import (
+	"fmt"
+	"iter"
+	"maps"
+	"quinto/core"
+	"quinto/data"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const fieldKeyPrefix = "field-"
+const fieldDictionaryKey = "meta-field-dictionary"
+const fieldBoostsKey = "meta-field-boosts"
+
+type fieldBoosts struct {
+	mutex  sync.RWMutex
+	boosts map[string]float64
+}
+
+func (fb *fieldBoosts) set(field string, boost float64) bool {
+	fb.mutex.Lock()
+	defer fb.mutex.Unlock()
+	if current, exists := fb.boosts[field]; exists && current == boost {
+		return false
+	}
+	fb.boosts[field] = boost
+	return true
+}
+
+func (fb *fieldBoosts) get(field string) (float64, bool) {
+	fb.mutex.RLock()
+	defer fb.mutex.RUnlock()
+	boost, exists := fb.boosts[field]
+	return boost, exists
+}
+
+func (fb *fieldBoosts) snapshot() map[string]float64 {
+	fb.mutex.RLock()
+	defer fb.mutex.RUnlock()
+	return maps.Clone(fb.boosts)
+}
+
+func fieldTermKey(field string, term string) string {
+	return field + "-" + term
+}
+
+func (pm *PersistenceManager) loadFieldDictionary() {
+	pm.loadDictionary(fieldDictionaryKey, &pm.fieldTerms)
+}
+
+func (pm *PersistenceManager) storeFieldDictionary() error {
+	return pm.storeDictionary(fieldDictionaryKey, &pm.fieldTerms)
+}
+
+func (pm *PersistenceManager) loadFieldBoosts() {
+	pm.fieldBoosts.boosts = make(map[string]float64)
+	reader, exists := pm.config.IoHandler.getReader(fieldBoostsKey)
+	if !exists || reader == nil {
+		return
+	}
+	countString, err := decodeStringFromDisk(reader)
+	panicWhenSomeErrorsOccurred([]error{err})
+	count, _ := strconv.Atoi(countString)
+	for range count {
+		errors := [2]error{}
+		var field, boostString string
+		field, errors[0] = decodeStringFromDisk(reader)
+		boostString, errors[1] = decodeStringFromDisk(reader)
+		panicWhenSomeErrorsOccurred(errors[:])
+		boost, _ := strconv.ParseFloat(boostString, 64)
+		pm.fieldBoosts.set(field, boost)
+	}
+}
+
+func (pm *PersistenceManager) storeFieldBoosts() error {
+	writer, finalize, err := pm.config.IoHandler.getWriter(fieldBoostsKey)
+	if err != nil {
+		return err
+	}
+	boosts := pm.fieldBoosts.snapshot()
+	if err := encodeStringToDisk(writer, fmt.Sprint(len(boosts))); err != nil {
+		return err
+	}
+	for _, field := range slices.Sorted(maps.Keys(boosts)) {
+		if err := encodeStringToDisk(writer, field); err != nil {
+			return err
+		}
+		if err := encodeStringToDisk(writer, strconv.FormatFloat(boosts[field], 'g', -1, 64)); err != nil {
+			return err
+		}
+	}
+	return finalize()
+}
+
+func (pm *PersistenceManager) FieldBoost(field string) float64 {
+	if boost, exists := pm.fieldBoosts.get(field); exists && boost > 0 {
+		return boost
+	}
+	return 1
+}
+
+func (pm *PersistenceManager) IterateOverFieldTerms(field string, term string) iter.Seq[core.TermTracker] {
+	return pm.iterateOverChain(fieldKeyPrefix + fieldTermKey(field, term))
+}
+
+func (pm *PersistenceManager) IterateOverFieldDictionary(field string, prefix string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for fieldTerm := range pm.fieldTerms.iterateWithPrefix(fieldTermKey(field, prefix)) {
+			if !yield(strings.TrimPrefix(fieldTerm, fieldTermKey(field, ""))) {
+				return
+			}
+		}
+	}
+}
+
+func (pm *PersistenceManager) StoreNewFieldedDocument(fields []core.DocumentField) (core.DocumentId, error) {
+	pm.updatesMutex.RLock()
+	defer pm.updatesMutex.RUnlock()
+	docId := core.DocumentId(pm.documentCounter.Add(1))
+	pm.indexFieldedDocument(docId, fields)
+	return docId, nil
+}
+
+func (pm *PersistenceManager) UpsertFieldedDocument(externalId string, fields []core.DocumentField) (core.DocumentId, error) {
+	return pm.upsert(externalId, func(docId core.DocumentId) error {
+		pm.indexFieldedDocument(docId, fields)
+		return nil
+	})
+}
+
+func (pm *PersistenceManager) indexFieldedDocument(docId core.DocumentId, fields []core.DocumentField) {
+	for _, field := range fields {
+		if field.Boost > 0 && pm.fieldBoosts.set(field.Name, field.Boost) {
+			pm.fieldBoostsSection.pending.Store(true)
+		}
+	}
+	tokens := []core.Token{}
+	tokensByField := map[string][]core.Token{}
+	for field, token := range core.IterateFieldTokens(fields) {
+		tokens = append(tokens, token)
+		tokensByField[field] = append(tokensByField[field], token)
+	}
+	pm.indexDocument(docId, data.NewSliceIterator(tokens))
+	for field, fieldTokens := range tokensByField {
+		for term, trackers := range groupTokensByTerm(docId, data.NewSliceIterator(fieldTokens)) {
+			chunk := pm.locateChunk(fieldKeyPrefix+fieldTermKey(field, term), trackers[0])
+			chunk.insertIterable(data.NewSliceIterator(trackers))
+			pm.markForWriteBack(chunk)
+			chunk.unpin()
+			if pm.fieldTerms.insert(fieldTermKey(field, term)) {
+				pm.fieldTermsSection.pending.Store(true)
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package persistence
+
+import (
+	"quinto/core"
+	"quinto/data"
+	"slices"
+	"testing"
+)
+
+func UtilFieldsFromWords(fields map[string][]string, order ...string) []core.DocumentField {
+	documentFields := []core.DocumentField{}
+	for _, name := range order {
+		documentFields = append(documentFields, core.DocumentField{
+			Name:   name,
+			Tokens: data.NewSliceIterator(UtilTokensFromWords(fields[name]...)),
+		})
+	}
+	return documentFields
+}
+
+func TestFieldedDocumentPostings(t *testing.T) {
+	manager := NewPersistenceManager(PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       newMockDiskHandler(),
+	})
+	defer manager.Close()
+
+	fields := map[string][]string{"body": {"music", "guitar", "band"}, "title": {"guitar"}}
+	docId, err := manager.StoreNewFieldedDocument(UtilFieldsFromWords(fields, "body", "title"))
+	if err != nil {
+		t.Fatalf("Failed to store fielded document: %v", err)
+	}
+
+	titleOffset := core.TermPosition(3 + core.FieldPositionGap)
+	if trackers := data.CollectAsSlice(manager.IterateOverFieldTerms("title", "guitar")); !slices.Equal(trackers, []core.TermTracker{{DocId: docId, Position: titleOffset}}) {
+		t.Errorf("Expected the title term after the gap, got %v", trackers)
+	}
+	if trackers := data.CollectAsSlice(manager.IterateOverFieldTerms("body", "guitar")); !slices.Equal(trackers, []core.TermTracker{{DocId: docId, Position: 1}}) {
+		t.Errorf("Expected the body term at its own position, got %v", trackers)
+	}
+	if iters := data.CountIterations(manager.IterateOverFieldTerms("title", "music")); iters != 0 {
+		t.Errorf("Expected the body terms not to be found in the title, got %d", iters)
+	}
+	if iters := data.CountIterations(manager.IterateOverTerms("guitar")); iters != 2 {
+		t.Errorf("Expected the terms of every field to be indexed for the whole document, got %d", iters)
+	}
+	if length := manager.DocumentLength(docId); length != 4 {
+		t.Errorf("Expected the document length to include every field, got %d", length)
+	}
+	if terms := data.CollectAsSlice(manager.IterateOverFieldDictionary("body", "b")); !slices.Equal(terms, []string{"band"}) {
+		t.Errorf("Expected the field dictionary to be scoped to the field, got %v", terms)
+	}
+}
+
+func TestFieldedDocumentsSurviveUpsertAndRestart(t *testing.T) {
+	handler := newMockDiskHandler()
+	config := PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       handler,
+	}
+	manager := NewPersistenceManager(config)
+
+	oldFields := map[string][]string{"body": {"drums"}, "title": {"drums"}}
+	newFields := map[string][]string{"body": {"guitar"}, "title": {"guitar"}}
+	oldDocId, _ := manager.UpsertFieldedDocument("/docs/a.txt", UtilFieldsFromWords(oldFields, "body", "title"))
+	newDocId, _ := manager.UpsertFieldedDocument("/docs/a.txt", UtilFieldsFromWords(newFields, "body", "title"))
+	if oldDocId == newDocId {
+		t.Fatalf("Expected the new version to get a fresh document-id")
+	}
+	manager.Compact()
+	if err := manager.Close(); err != nil {
+		t.Fatalf("Failed to close persistence manager: %v", err)
+	}
+
+	reopened := NewPersistenceManager(config)
+	defer reopened.Close()
+
+	if iters := data.CountIterations(reopened.IterateOverFieldTerms("title", "drums")); iters != 0 {
+		t.Errorf("Expected the old version to be gone, got %d trackers", iters)
+	}
+	trackers := data.CollectAsSlice(reopened.IterateOverFieldTerms("title", "guitar"))
+	if len(trackers) != 1 || trackers[0].DocId != newDocId {
+		t.Errorf("Expected the new version in the title field, got %v", trackers)
+	}
+	if terms := data.CollectAsSlice(reopened.IterateOverFieldDictionary("title", "")); !slices.Equal(terms, []string{"guitar"}) {
+		t.Errorf("Expected the field dictionary to be persisted without pruned terms, got %v", terms)
+	}
+}
+
+func TestFieldBoostsSurviveRestart(t *testing.T) {
+	handler := newMockDiskHandler()
+	config := PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       handler,
+	}
+	manager := NewPersistenceManager(config)
+
+	fields := UtilFieldsFromWords(map[string][]string{"body": {"music"}, "title": {"guitar"}}, "body", "title")
+	fields[1].Boost = 2.5
+	if _, err := manager.StoreNewFieldedDocument(fields); err != nil {
+		t.Fatalf("Failed to store fielded document: %v", err)
+	}
+	if boost := manager.FieldBoost("title"); boost != 2.5 {
+		t.Errorf("Expected the title to be boosted by 2.5, got %v", boost)
+	}
+	if err := manager.Close(); err != nil {
+		t.Fatalf("Failed to close persistence manager: %v", err)
+	}
+
+	reopened := NewPersistenceManager(config)
+	defer reopened.Close()
+
+	if boost := reopened.FieldBoost("title"); boost != 2.5 {
+		t.Errorf("Expected the title boost to survive a restart, got %v", boost)
+	}
+	if boost := reopened.FieldBoost("body"); boost != 1 {
+		t.Errorf("Expected the body not to be boosted, got %v", boost)
+	}
+	if boost := reopened.FieldBoost("unknown"); boost != 1 {
+		t.Errorf("Expected unknown fields not to be boosted, got %v", boost)
+	}
+}
@@ -11,7 +11,11 @@ sorted array of `core.TermTracker` objects. It is supposed to be the smallest un
 in which a full inverted list can be concurrently accessed. A full inverted list is
 composed of one or more index chunks. An `indexChunk` must be read and written
 on disk. Multiple readers can read from it concurrently, but only one writer can
-update it. After an update, the `indexChunk` must be written back to disk.
+update it. After an update, the `indexChunk` must be written back to disk. The dirty
+flag is only raised by writers, and it is only cleared while holding the read lock,
+so a chunk updated while being written back can never be taken as clean. A chunk is
+pinned by whoever is using it (e.g. iterating over it, or inserting into it), and it
+must be unpinned once done: pinned chunks are never evicted from the cache.
 ==================================================================================*/
 
 package persistence
@@ -22,16 +26,19 @@ import (
 	"quinto/core"
 	"quinto/data"
 	"strconv"
+	"sync/atomic"
 )
 
 type indexChunk struct {
 	termTrackers     data.SortedArray[core.TermTracker]
 	chunkKey         string
 	nextChunkKey     string
-	pendingWriteBack bool
+	pendingWriteBack atomic.Bool
 	splitCounter     uint64
 	handler          diskHandler
 	rwMutex          core.ReadWriteMutex
+	queuedForSync    atomic.Bool
+	pins             atomic.Int64
 }
 
 func panicWhenSomeErrorsOccurred(err []error) {
@@ -56,13 +63,12 @@ func newSortedArrayOfTermTrackers() data.SortedArray[core.TermTracker] {
 
 func newIndexChunk(chunkKey string, handler diskHandler) *indexChunk {
 	chunk := &indexChunk{
-		termTrackers:     newSortedArrayOfTermTrackers(),
-		chunkKey:         chunkKey,
-		nextChunkKey:     "",
-		pendingWriteBack: false,
-		handler:          handler,
-		splitCounter:     0,
-		rwMutex:          core.NewWritersFirstRWMutex(),
+		termTrackers: newSortedArrayOfTermTrackers(),
+		chunkKey:     chunkKey,
+		nextChunkKey: "",
+		handler:      handler,
+		splitCounter: 0,
+		rwMutex:      core.NewWritersFirstRWMutex(),
 	}
 	reader, exists := handler.getReader(chunkKey)
 	if !exists || reader == nil {
@@ -81,19 +87,31 @@ func newIndexChunk(chunkKey string, handler diskHandler) *indexChunk {
 	return chunk
 }
 
-func (chunk *indexChunk) writeBack() {
+func (chunk *indexChunk) writeBack() error {
 	chunk.rwMutex.RLock()
 	defer chunk.rwMutex.RUnlock()
-	if !chunk.pendingWriteBack {
-		return
+	if !chunk.pendingWriteBack.Load() {
+		return nil
 	}
-	writer, finalize, _ := chunk.handler.getWriter(chunk.chunkKey)
-	defer finalize()
-	encodeStringToDisk(writer, chunk.chunkKey)
-	encodeStringToDisk(writer, chunk.nextChunkKey)
-	encodeStringToDisk(writer, fmt.Sprint(chunk.splitCounter))
-	encodeTermTrackersToDisk(writer, chunk.iterate())
-	chunk.pendingWriteBack = false
+	writer, finalize, err := chunk.handler.getWriter(chunk.chunkKey)
+	if err != nil {
+		return err
+	}
+	errors := [4]error{}
+	errors[0] = encodeStringToDisk(writer, chunk.chunkKey)
+	errors[1] = encodeStringToDisk(writer, chunk.nextChunkKey)
+	errors[2] = encodeStringToDisk(writer, fmt.Sprint(chunk.splitCounter))
+	errors[3] = encodeTermTrackersToDisk(writer, chunk.termTrackers.Iterate())
+	for _, e := range errors {
+		if e != nil {
+			return e
+		}
+	}
+	if err := finalize(); err != nil {
+		return err
+	}
+	chunk.pendingWriteBack.Store(false)
+	return nil
 }
 
 func (chunk *indexChunk) iterate() iter.Seq[core.TermTracker] {
@@ -112,31 +130,92 @@ func (chunk *indexChunk) insertIterable(termsIterator iter.Seq[core.TermTracker]
 	chunk.rwMutex.Lock()
 	defer chunk.rwMutex.Unlock()
 	for term := range termsIterator {
-		inserted := chunk.termTrackers.Insert(term)
-		chunk.pendingWriteBack = chunk.pendingWriteBack || inserted
+		if chunk.termTrackers.Insert(term) {
+			chunk.pendingWriteBack.Store(true)
+		}
 	}
 }
 
-func (chunk *indexChunk) split() *indexChunk {
+func (chunk *indexChunk) removeDocuments(deleted *data.Bitmap) []core.DocumentId {
+	chunk.rwMutex.Lock()
+	defer chunk.rwMutex.Unlock()
+	removedDocIds := []core.DocumentId{}
+	chunk.termTrackers.RemoveIf(func(tracker core.TermTracker) bool {
+		if !deleted.Contains(uint64(tracker.DocId)) {
+			return false
+		}
+		if len(removedDocIds) == 0 || removedDocIds[len(removedDocIds)-1] != tracker.DocId {
+			removedDocIds = append(removedDocIds, tracker.DocId)
+		}
+		return true
+	})
+	if len(removedDocIds) > 0 {
+		chunk.pendingWriteBack.Store(true)
+	}
+	return removedDocIds
+}
+
+func (chunk *indexChunk) pin() {
+	chunk.pins.Add(1)
+}
+
+func (chunk *indexChunk) unpin() {
+	chunk.pins.Add(-1)
+}
+
+func (chunk *indexChunk) isPinned() bool {
+	return chunk.pins.Load() > 0
+}
+
+func (chunk *indexChunk) next() string {
+	chunk.rwMutex.RLock()
+	defer chunk.rwMutex.RUnlock()
+	return chunk.nextChunkKey
+}
+
+func (chunk *indexChunk) lowest() (core.TermTracker, bool) {
+	chunk.rwMutex.RLock()
+	defer chunk.rwMutex.RUnlock()
+	return chunk.termTrackers.Lowest()
+}
+
+func (chunk *indexChunk) isEmpty() bool {
+	chunk.rwMutex.RLock()
+	defer chunk.rwMutex.RUnlock()
+	return chunk.termTrackers.Size() == 0
+}
+
+func (chunk *indexChunk) isOversized(maxSize int) bool {
+	chunk.rwMutex.RLock()
+	defer chunk.rwMutex.RUnlock()
+	return chunk.termTrackers.Size() > maxSize
+}
+
+func (chunk *indexChunk) splitWhenOversized(maxSize int, register func(newChunk *indexChunk)) *indexChunk {
+	if !chunk.isOversized(maxSize) {
+		return nil
+	}
 	chunk.rwMutex.Lock()
-	chunk.splitCounter++
 	defer chunk.rwMutex.Unlock()
+	if chunk.termTrackers.Size() <= maxSize {
+		return nil
+	}
+	chunk.splitCounter++
 	newChunk := &indexChunk{
-		termTrackers:     newSortedArrayOfTermTrackers(),
-		chunkKey:         chunk.chunkKey + "-" + fmt.Sprint(chunk.splitCounter),
-		nextChunkKey:     chunk.nextChunkKey,
-		pendingWriteBack: false,
-		handler:          chunk.handler,
-		rwMutex:          core.NewWritersFirstRWMutex(),
+		termTrackers: newSortedArrayOfTermTrackers(),
+		chunkKey:     chunk.chunkKey + "-" + fmt.Sprint(chunk.splitCounter),
+		nextChunkKey: chunk.nextChunkKey,
+		handler:      chunk.handler,
+		rwMutex:      core.NewWritersFirstRWMutex(),
 	}
+	newChunk.pendingWriteBack.Store(true)
 	chunk.nextChunkKey = newChunk.chunkKey
-	counter := 0
-	for tracker := range chunk.iterate() {
-		if counter >= chunk.termTrackers.Size()/2 {
-			newChunk.termTrackers.Insert(tracker)
-			chunk.termTrackers.Remove(tracker)
-		}
-		counter++
+	chunk.pendingWriteBack.Store(true)
+	allTrackers := data.CollectAsSlice(chunk.termTrackers.Iterate())
+	for _, tracker := range allTrackers[len(allTrackers)/2:] {
+		newChunk.termTrackers.Insert(tracker)
+		chunk.termTrackers.Remove(tracker)
 	}
+	register(newChunk)
 	return newChunk
 }
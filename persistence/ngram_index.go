@@ -0,0 +1,58 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+This file contains the n-gram index of the `PersistenceManager`. The inverted lists
+of the n-grams are chains of index chunks, exactly like the ones of the terms, but
+they live in their own keyspace ("ngram-<n-gram>"), so that an n-gram never collides
+with a term, and they are not accounted in the statistics of the documents. Since
+chunk keys are derived from the n-grams themselves, every indexed n-gram is recorded
+in a dictionary of its own, which is needed to compact the n-gram chains once some
+documents have been deleted.
+==================================================================================*/
+
+package persistence
+
+import (
+	"iter"
+	"quinto/core"
+	"quinto/data"
+)
+
+const nGramKeyPrefix = "ngram-"
+const nGramDictionaryKey = "meta-ngram-dictionary"
+
+func (pm *PersistenceManager) loadNGramDictionary() {
+	pm.loadDictionary(nGramDictionaryKey, &pm.nGrams)
+}
+
+func (pm *PersistenceManager) storeNGramDictionary() error {
+	return pm.storeDictionary(nGramDictionaryKey, &pm.nGrams)
+}
+
+func (pm *PersistenceManager) IterateOverNGrams(nGram string) iter.Seq[core.TermTracker] {
+	return pm.iterateOverChain(nGramKeyPrefix + nGram)
+}
+
+func (pm *PersistenceManager) StoreDocumentNGrams(docId core.DocumentId, nGrams iter.Seq[core.Token]) error {
+	pm.updatesMutex.RLock()
+	defer pm.updatesMutex.RUnlock()
+	pm.indexNGrams(docId, nGrams)
+	return nil
+}
+
+func (pm *PersistenceManager) indexNGrams(docId core.DocumentId, nGrams iter.Seq[core.Token]) {
+	for nGram, trackers := range groupTokensByTerm(docId, nGrams) {
+		chunk := pm.locateChunk(nGramKeyPrefix+nGram, trackers[0])
+		chunk.insertIterable(data.NewSliceIterator(trackers))
+		pm.markForWriteBack(chunk)
+		chunk.unpin()
+		if pm.nGrams.insert(nGram) {
+			pm.nGramsSection.pending.Store(true)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package persistence
+
+import (
+	"quinto/core"
+	"quinto/data"
+	"slices"
+	"testing"
+)
+
+func TestNGramsAreKeptApartFromTerms(t *testing.T) {
+	manager := NewPersistenceManager(PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       newMockDiskHandler(),
+	})
+	defer manager.Close()
+
+	docId := UtilStoreWords(t, manager, "xk", "452")
+	nGrams := []core.Token{{StemmedText: "xk-", Position: 0}, {StemmedText: "452", Position: 1}}
+	if err := manager.StoreDocumentNGrams(docId, data.NewSliceIterator(nGrams)); err != nil {
+		t.Fatalf("Failed to store n-grams: %v", err)
+	}
+
+	trackers := data.CollectAsSlice(manager.IterateOverNGrams("452"))
+	if !slices.Equal(trackers, []core.TermTracker{{DocId: docId, Position: 1}}) {
+		t.Errorf("Expected the n-gram to be found at position 1, got %v", trackers)
+	}
+	if iters := data.CountIterations(manager.IterateOverTerms("xk-")); iters != 0 {
+		t.Errorf("Expected n-grams not to be found as terms, got %d", iters)
+	}
+	if slices.Contains(data.CollectAsSlice(manager.IterateOverDictionary("")), "xk-") {
+		t.Errorf("Expected n-grams not to be added to the term dictionary")
+	}
+	if length := manager.DocumentLength(docId); length != 2 {
+		t.Errorf("Expected n-grams not to affect the length of the document, got %d", length)
+	}
+}
+
+func TestNGramsOfDeletedDocumentsAreCompacted(t *testing.T) {
+	handler := newMockDiskHandler()
+	config := PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       handler,
+	}
+	manager := NewPersistenceManager(config)
+
+	first := UtilStoreWords(t, manager, "xk45210")
+	second := UtilStoreWords(t, manager, "ab4521")
+	manager.StoreDocumentNGrams(first, data.NewSliceIterator([]core.Token{{StemmedText: "452"}}))
+	manager.StoreDocumentNGrams(second, data.NewSliceIterator([]core.Token{{StemmedText: "452"}}))
+	manager.DeleteDocument(first)
+	manager.Compact()
+	if err := manager.Close(); err != nil {
+		t.Fatalf("Failed to close persistence manager: %v", err)
+	}
+
+	reopened := NewPersistenceManager(config)
+	defer reopened.Close()
+
+	trackers := data.CollectAsSlice(reopened.IterateOverNGrams("452"))
+	if !slices.Equal(trackers, []core.TermTracker{{DocId: second, Position: 0}}) {
+		t.Errorf("Expected only the n-gram of the second document, got %v", trackers)
+	}
+	if !slices.Contains(reopened.nGrams.snapshot(), "452") {
+		t.Errorf("Expected the n-gram dictionary to be persisted")
+	}
+	if chunk := reopened.retrieveChunkFromDisk(nGramKeyPrefix + "452"); chunk == nil || chunk.termTrackers.Size() != 1 {
+		t.Errorf("Expected the compacted n-gram chunk to be written back")
+	}
+}
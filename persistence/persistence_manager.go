@@ -4,78 +4,166 @@ import (
 	"iter"
 	"quinto/core"
 	"quinto/data"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
+const termKeyPrefix = "term-"
+const documentCounterKey = "meta-document-counter"
+
 type wrappedIndexChunk struct {
 	chunk     *indexChunk
 	listEntry data.ConcurrentListEntry[string]
 }
 
 type PersistenceConfig struct {
-	MaxCachedChunks int64
-	MaxChunkSize    int
-	IoHandler       diskHandler
+	MaxCachedChunks     int64
+	MaxChunkSize        int
+	WriteBackInterval   time.Duration
+	WriteBackBatchSize  int
+	CompactionThreshold int64
+	IoHandler           diskHandler
 }
 
 type PersistenceManager struct {
-	cacheSize   atomic.Int64
-	config      PersistenceConfig
-	chunkPool   data.ConcurrentMap[string, wrappedIndexChunk]
-	accessList  data.ConcurrentList[string]
-	pendingSync data.ConcurrentQueue[string]
+	cacheSize            atomic.Int64
+	documentCounter      atomic.Uint64
+	documentsCount       atomic.Uint64
+	totalLength          atomic.Uint64
+	statisticsSection    persistedSection
+	storedFieldsSection  persistedSection
+	dictionarySection    persistedSection
+	nGramsSection        persistedSection
+	fieldTermsSection    persistedSection
+	fieldBoostsSection   persistedSection
+	metadataSections     []*persistedSection
+	commitPending        atomic.Bool
+	uncompactedDeletions atomic.Int64
+	config               PersistenceConfig
+	chunkPool            data.ConcurrentMap[string, wrappedIndexChunk]
+	accessList           data.ConcurrentList[string]
+	pendingSync          *data.ConcurrentQueue[string]
+	dictionary           termDictionary
+	nGrams               termDictionary
+	fieldTerms           termDictionary
+	fieldBoosts          fieldBoosts
+	frequencies          documentFrequencies
+	deleted              tombstones
+	externalIds          externalIds
+	stored               storedFields
+	upsertMutex          sync.Mutex
+	updatesMutex         sync.RWMutex
+	cacheMutex           sync.Mutex
+	reservationMutex     sync.Mutex
+	reservedCounter      uint64
+	writeBackWorker      writeBackWorker
 }
 
 func NewPersistenceManager(config PersistenceConfig) *PersistenceManager {
-	return &PersistenceManager{
+	if config.WriteBackInterval <= 0 {
+		config.WriteBackInterval = DefaultWriteBackInterval
+	}
+	if config.WriteBackBatchSize <= 0 {
+		config.WriteBackBatchSize = DefaultWriteBackBatchSize
+	}
+	if config.CompactionThreshold <= 0 {
+		config.CompactionThreshold = DefaultCompactionThreshold
+	}
+	pm := &PersistenceManager{
 		config:      config,
 		chunkPool:   *data.NewConcurrentMap[string, wrappedIndexChunk](),
 		accessList:  *data.NewLinkedList[string](),
-		pendingSync: *data.NewConcurrentQueue[string](),
+		pendingSync: data.NewConcurrentQueue[string](),
 	}
+	pm.initMetadataSections()
+	pm.loadDocumentCommit()
+	pm.loadStatistics()
+	pm.loadTermDictionary()
+	pm.loadNGramDictionary()
+	pm.loadFieldDictionary()
+	pm.loadFieldBoosts()
+	pm.loadStoredLanguages()
+	pm.startWriteBackWorker()
+	return pm
 }
 
 func (pm *PersistenceManager) evictNotPendingLRU() {
-	for {
-		for listEntry := range pm.accessList.IterateBackwards() {
-			wrappedChunk, exists := pm.chunkPool.Get(listEntry.Value())
-			if exists && !wrappedChunk.chunk.pendingWriteBack {
-				pm.chunkPool.Delete(listEntry.Value())
-				listEntry.Remove()
-				pm.cacheSize.Add(-1)
-				return
-			}
+	pm.cacheMutex.Lock()
+	var leastRecentlyUsedDirty *indexChunk = nil
+	for listEntry := range pm.accessList.IterateBackwards() {
+		wrappedChunk, exists := pm.chunkPool.Get(listEntry.Value())
+		if !exists || wrappedChunk.chunk.isPinned() {
+			continue
 		}
+		if !wrappedChunk.chunk.pendingWriteBack.Load() {
+			pm.evict(wrappedChunk)
+			pm.cacheMutex.Unlock()
+			return
+		}
+		if leastRecentlyUsedDirty == nil {
+			leastRecentlyUsedDirty = wrappedChunk.chunk
+		}
+	}
+	if leastRecentlyUsedDirty == nil {
+		pm.cacheMutex.Unlock()
+		return
 	}
+	leastRecentlyUsedDirty.pin()
+	pm.cacheMutex.Unlock()
+	written := pm.reserveDocumentIds() == nil && leastRecentlyUsedDirty.writeBack() == nil
+	pm.cacheMutex.Lock()
+	defer pm.cacheMutex.Unlock()
+	leastRecentlyUsedDirty.unpin()
+	wrappedChunk, exists := pm.chunkPool.Get(leastRecentlyUsedDirty.chunkKey)
+	if written && exists && wrappedChunk.chunk == leastRecentlyUsedDirty &&
+		!leastRecentlyUsedDirty.isPinned() && !leastRecentlyUsedDirty.pendingWriteBack.Load() {
+		pm.evict(wrappedChunk)
+	}
+}
+
+func (pm *PersistenceManager) evict(wrappedChunk wrappedIndexChunk) {
+	pm.chunkPool.Delete(wrappedChunk.chunk.chunkKey)
+	wrappedChunk.listEntry.Remove()
+	pm.cacheSize.Add(-1)
+}
+
+func (pm *PersistenceManager) cache(chunk *indexChunk) {
+	pm.chunkPool.Set(chunk.chunkKey, wrappedIndexChunk{
+		listEntry: pm.accessList.InsertFront(chunk.chunkKey),
+		chunk:     chunk,
+	})
+	pm.cacheSize.Add(1)
 }
 
 func (pm *PersistenceManager) retrieveChunkFromCache(key string) *indexChunk {
+	pm.cacheMutex.Lock()
+	defer pm.cacheMutex.Unlock()
 	wrappedChunk, exists := pm.chunkPool.Get(key)
-	if exists {
-		wrappedChunk.listEntry.Remove()
-		pm.accessList.InsertFront(key)
-		return wrappedChunk.chunk
+	if !exists {
+		return nil
 	}
-	return nil
+	wrappedChunk.listEntry.Remove()
+	wrappedChunk.listEntry = pm.accessList.InsertFront(key)
+	pm.chunkPool.Set(key, wrappedChunk)
+	wrappedChunk.chunk.pin()
+	return wrappedChunk.chunk
 }
 
 func (pm *PersistenceManager) retrieveChunkFromDisk(key string) *indexChunk {
-	var chunkPtr *indexChunk = nil
-	for !pm.chunkPool.Contains(key) {
-		if pm.cacheSize.Load() >= pm.config.MaxCachedChunks {
-			pm.evictNotPendingLRU()
-		}
-		chunkPtr = newIndexChunk(key, pm.config.IoHandler)
-		pm.cacheSize.Add(1)
-		pm.chunkPool.Set(key, wrappedIndexChunk{
-			listEntry: pm.accessList.InsertFront(key),
-			chunk:     chunkPtr,
-		})
-		if chunkPtr.pendingWriteBack {
-			pm.pendingSync.Push(key)
-		}
+	if pm.cacheSize.Load() >= pm.config.MaxCachedChunks {
+		pm.evictNotPendingLRU()
+	}
+	pm.cacheMutex.Lock()
+	defer pm.cacheMutex.Unlock()
+	if cachedChunk, exists := pm.chunkPool.Get(key); exists {
+		cachedChunk.chunk.pin()
+		return cachedChunk.chunk
 	}
-	return chunkPtr
+	chunk := newIndexChunk(key, pm.config.IoHandler)
+	chunk.pin()
+	pm.cache(chunk)
+	return chunk
 }
 
 func (pm *PersistenceManager) retrieveChunk(key string) *indexChunk {
@@ -83,28 +171,123 @@ func (pm *PersistenceManager) retrieveChunk(key string) *indexChunk {
 	if chunk == nil {
 		return pm.retrieveChunkFromDisk(key)
 	}
-	if chunk.termTrackers.Size() > int(pm.config.MaxChunkSize) {
-		new_chunk := chunk.split()
-		pm.chunkPool.Set(new_chunk.chunkKey, wrappedIndexChunk{
-			listEntry: pm.accessList.InsertFront(new_chunk.chunkKey),
-			chunk:     new_chunk,
-		})
-		pm.cacheSize.Add(1)
-		pm.pendingSync.Push(new_chunk.chunkKey)
+	new_chunk := chunk.splitWhenOversized(pm.config.MaxChunkSize, func(new_chunk *indexChunk) {
+		pm.cacheMutex.Lock()
+		defer pm.cacheMutex.Unlock()
+		pm.cache(new_chunk)
+	})
+	if new_chunk != nil {
+		pm.markForWriteBack(new_chunk)
+		pm.markForWriteBack(chunk)
 	}
 	return chunk
 }
 
-func (pm *PersistenceManager) IterateTerms(term string) iter.Seq[core.TermTracker] {
+func (pm *PersistenceManager) IterateOverTerms(term string) iter.Seq[core.TermTracker] {
+	return pm.iterateOverChain(termKeyPrefix + term)
+}
+
+func (pm *PersistenceManager) iterateOverChain(headKey string) iter.Seq[core.TermTracker] {
 	return func(yield func(core.TermTracker) bool) {
-		chunk := pm.retrieveChunk("term-" + term)
-		for chunk != nil && chunk.termTrackers.Size() > 0 {
-			for term := range chunk.iterate() {
-				if !yield(term) {
-					break
+		chunk := pm.retrieveChunk(headKey)
+		defer func() { chunk.unpin() }()
+		for {
+			for tracker := range chunk.iterate() {
+				if pm.isDeleted(tracker.DocId) {
+					continue
+				}
+				if !yield(tracker) {
+					return
 				}
 			}
-			chunk = pm.retrieveChunk(chunk.nextChunkKey)
+			nextChunkKey := chunk.next()
+			if nextChunkKey == "" {
+				return
+			}
+			nextChunk := pm.retrieveChunk(nextChunkKey)
+			chunk.unpin()
+			chunk = nextChunk
+		}
+	}
+}
+
+func (pm *PersistenceManager) locateChunk(headKey string, tracker core.TermTracker) *indexChunk {
+	chunk := pm.retrieveChunk(headKey)
+	for nextChunkKey := chunk.next(); nextChunkKey != ""; nextChunkKey = chunk.next() {
+		nextChunk := pm.retrieveChunk(nextChunkKey)
+		lowest, exists := nextChunk.lowest()
+		precedesNextChunk := exists && (tracker.DocId < lowest.DocId ||
+			(tracker.DocId == lowest.DocId && tracker.Position < lowest.Position))
+		if !exists || precedesNextChunk {
+			nextChunk.unpin()
+			break
+		}
+		chunk.unpin()
+		chunk = nextChunk
+	}
+	return chunk
+}
+
+func groupTokensByTerm(docId core.DocumentId, toks iter.Seq[core.Token]) map[string][]core.TermTracker {
+	groups := make(map[string][]core.TermTracker)
+	for tok := range toks {
+		groups[tok.StemmedText] = append(groups[tok.StemmedText], core.TermTracker{
+			DocId:    docId,
+			Position: tok.Position,
+		})
+	}
+	return groups
+}
+
+func (pm *PersistenceManager) StoreNewDocument(toks iter.Seq[core.Token]) (core.DocumentId, error) {
+	pm.updatesMutex.RLock()
+	defer pm.updatesMutex.RUnlock()
+	docId := core.DocumentId(pm.documentCounter.Add(1))
+	pm.indexDocument(docId, toks)
+	return docId, nil
+}
+
+func (pm *PersistenceManager) IndexDocument(document core.IndexedDocument) (core.DocumentId, error) {
+	index := func(docId core.DocumentId) error {
+		if len(document.Fields) > 0 {
+			pm.indexFieldedDocument(docId, document.Fields)
+		} else {
+			pm.indexDocument(docId, document.Tokens)
+		}
+		if document.NGrams != nil {
+			pm.indexNGrams(docId, document.NGrams)
+		}
+		return pm.StoreDocumentFields(docId, document.Stored)
+	}
+	if document.ExternalId != "" {
+		return pm.upsert(document.ExternalId, index)
+	}
+	pm.updatesMutex.RLock()
+	defer pm.updatesMutex.RUnlock()
+	docId := core.DocumentId(pm.documentCounter.Add(1))
+	pm.deleted.hide(docId)
+	if err := index(docId); err != nil {
+		pm.discardDocument(docId)
+		return 0, err
+	}
+	pm.deleted.replace(0, docId)
+	return docId, nil
+}
+
+func (pm *PersistenceManager) indexDocument(docId core.DocumentId, toks iter.Seq[core.Token]) {
+	documentLength := uint64(0)
+	for term, trackers := range groupTokensByTerm(docId, toks) {
+		chunk := pm.locateChunk(termKeyPrefix+term, trackers[0])
+		chunk.insertIterable(data.NewSliceIterator(trackers))
+		pm.markForWriteBack(chunk)
+		chunk.unpin()
+		if pm.dictionary.insert(term) {
+			pm.dictionarySection.pending.Store(true)
 		}
+		pm.frequencies.add(term, 1)
+		documentLength += uint64(len(trackers))
 	}
+	pm.recordDocumentLength(docId, documentLength)
+	pm.statisticsSection.pending.Store(true)
+	pm.commitPending.Store(true)
 }
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"quinto/core"
 	"quinto/data"
+	"quinto/search"
+	"slices"
 	"testing"
 )
 
@@ -54,7 +56,7 @@ func TestLoggingAllChunksWithPersistenceManager(t *testing.T) {
 		IoHandler:       handler,
 	})
 
-	hellos := manager.IterateTerms("hello")
+	hellos := manager.IterateOverTerms("hello")
 
 	for hello := range hellos {
 		t.Log(hello.DocId, ":", hello.Position, " ")
@@ -91,8 +93,8 @@ func TestRetrieveChunksWithPersistenceManager(t *testing.T) {
 		IoHandler:       handler,
 	})
 
-	hellos := manager.IterateTerms("hello")
-	worlds := manager.IterateTerms("world")
+	hellos := manager.IterateOverTerms("hello")
+	worlds := manager.IterateOverTerms("world")
 
 	if iters := data.CountIterations(hellos); len(hello_trackers) != iters {
 		t.Errorf("Expected %d hello trackers, got %d", len(hello_trackers), iters)
@@ -102,3 +104,272 @@ func TestRetrieveChunksWithPersistenceManager(t *testing.T) {
 		t.Errorf("Expected %d world trackers, got %d", len(world_trackers), iters)
 	}
 }
+
+func UtilTokensFromWords(words ...string) []core.Token {
+	tokens := []core.Token{}
+	for i, word := range words {
+		tokens = append(tokens, core.Token{
+			StemmedText:  word,
+			OriginalText: word,
+			Position:     core.TermPosition(i),
+		})
+	}
+	return tokens
+}
+
+func TestStoreNewDocumentWithPersistenceManager(t *testing.T) {
+	manager := NewPersistenceManager(PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       newMockDiskHandler(),
+	})
+
+	firstId, err1 := manager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("hello", "world", "hello")))
+	secondId, err2 := manager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("world")))
+	if err1 != nil || err2 != nil {
+		t.Fatalf("Failed to store documents: %v %v", err1, err2)
+	}
+
+	if firstId == secondId {
+		t.Fatalf("Expected distinct document ids, got %d twice", firstId)
+	}
+
+	hellos := data.CollectAsSlice(manager.IterateOverTerms("hello"))
+	expectedHellos := []core.TermTracker{{DocId: firstId, Position: 0}, {DocId: firstId, Position: 2}}
+	if len(hellos) != len(expectedHellos) {
+		t.Fatalf("Expected %d hello trackers, got %d", len(expectedHellos), len(hellos))
+	}
+	for expected, actual := range data.ZipSlices(expectedHellos, hellos) {
+		if expected != actual {
+			t.Errorf("Expected %v, got %v", expected, actual)
+		}
+	}
+
+	if iters := data.CountIterations(manager.IterateOverTerms("world")); iters != 2 {
+		t.Errorf("Expected 2 world trackers, got %d", iters)
+	}
+
+	if iters := data.CountIterations(manager.IterateOverTerms("guitar")); iters != 0 {
+		t.Errorf("Expected 0 guitar trackers, got %d", iters)
+	}
+}
+
+func TestStoreNewDocumentSurvivesRestart(t *testing.T) {
+	handler, err := NewFileSystemDiskHandler(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create disk handler: %v", err)
+	}
+	config := PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       handler,
+	}
+
+	firstManager := NewPersistenceManager(config)
+	firstId, _ := firstManager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("guitar", "music")))
+	if err := firstManager.Close(); err != nil {
+		t.Fatalf("Failed to close persistence manager: %v", err)
+	}
+
+	secondManager := NewPersistenceManager(config)
+	defer secondManager.Close()
+	secondId, _ := secondManager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("music")))
+
+	if secondId <= firstId {
+		t.Errorf("Expected document ids to keep growing across restarts, got %d then %d", firstId, secondId)
+	}
+
+	musics := data.CollectAsSlice(secondManager.IterateOverTerms("music"))
+	if len(musics) != 2 || musics[0].DocId != firstId || musics[1].DocId != secondId {
+		t.Errorf("Expected music trackers for documents %d and %d, got %v", firstId, secondId, musics)
+	}
+}
+
+func TestStoreNewDocumentWithChunkSplitting(t *testing.T) {
+	manager := NewPersistenceManager(PersistenceConfig{
+		MaxCachedChunks: 100,
+		MaxChunkSize:    4,
+		IoHandler:       newMockDiskHandler(),
+	})
+
+	const documentsCount = 50
+	for range documentsCount {
+		manager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("piano", "forte")))
+	}
+
+	if !manager.chunkPool.Contains("term-piano-1") {
+		t.Fatalf("Expected the piano inverted list to be split in multiple chunks")
+	}
+
+	pianos := data.CollectAsSlice(manager.IterateOverTerms("piano"))
+	if len(pianos) != documentsCount {
+		t.Fatalf("Expected %d piano trackers, got %d", documentsCount, len(pianos))
+	}
+	for i := 1; i < len(pianos); i++ {
+		if pianos[i-1].DocId >= pianos[i].DocId {
+			t.Fatalf("Expected trackers in ascending order, got %v before %v", pianos[i-1], pianos[i])
+		}
+	}
+}
+
+func TestSearchOverPersistenceManager(t *testing.T) {
+	manager := NewPersistenceManager(PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       newMockDiskHandler(),
+	})
+	var index core.ReverseIndex = manager
+
+	index.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("hello", "world")))
+	guitarId, _ := index.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("guitar", "music")))
+
+	fragments, err1 := search.SplitQuery("guitar AND music")
+	query, err2 := search.ParseQuery(fragments)
+	if err1 != nil || err2 != nil {
+		t.Fatalf("Failed to parse query: %v %v", err1, err2)
+	}
+
+	query.Init(index)
+	defer query.Close()
+
+	matchedDocuments := map[core.DocumentId]bool{}
+	for !query.Ended() {
+		if match := query.Run(); match.Success {
+			matchedDocuments[match.DocId] = true
+		}
+		query.Advance()
+	}
+
+	if len(matchedDocuments) != 1 || !matchedDocuments[guitarId] {
+		t.Errorf("Expected only document %d to match, got %v", guitarId, matchedDocuments)
+	}
+}
+
+func TestStatisticsSurviveRestart(t *testing.T) {
+	handler, err := NewFileSystemDiskHandler(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create disk handler: %v", err)
+	}
+	config := PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       handler,
+	}
+
+	firstManager := NewPersistenceManager(config)
+	shortId, _ := firstManager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("guitar", "music")))
+	longId, _ := firstManager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("music", "chess", "science", "love")))
+	if err := firstManager.Close(); err != nil {
+		t.Fatalf("Failed to close persistence manager: %v", err)
+	}
+
+	secondManager := NewPersistenceManager(config)
+	defer secondManager.Close()
+
+	if count := secondManager.DocumentsCount(); count != 2 {
+		t.Errorf("Expected 2 documents, got %d", count)
+	}
+	if length := secondManager.DocumentLength(shortId); length != 2 {
+		t.Errorf("Expected document %d to have length 2, got %d", shortId, length)
+	}
+	if length := secondManager.DocumentLength(longId); length != 4 {
+		t.Errorf("Expected document %d to have length 4, got %d", longId, length)
+	}
+	if average := secondManager.AverageDocumentLength(); average != 3 {
+		t.Errorf("Expected an average document length of 3, got %f", average)
+	}
+	if frequency := secondManager.DocumentFrequency("music"); frequency != 2 {
+		t.Errorf("Expected 'music' to appear in 2 documents, got %d", frequency)
+	}
+	if frequency := secondManager.DocumentFrequency("guitar"); frequency != 1 {
+		t.Errorf("Expected 'guitar' to appear in 1 document, got %d", frequency)
+	}
+}
+
+func TestIterateOverDocumentsWithPersistenceManager(t *testing.T) {
+	manager := NewPersistenceManager(PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    2,
+		IoHandler:       newMockDiskHandler(),
+	})
+	defer manager.Close()
+
+	expected := []core.DocumentId{}
+	for range 5 {
+		docId, _ := manager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("guitar")))
+		expected = append(expected, docId)
+	}
+
+	documents := data.CollectAsSlice(manager.IterateOverDocuments())
+	if !slices.Equal(documents, expected) {
+		t.Errorf("Expected documents %v, got %v", expected, documents)
+	}
+}
+
+func TestTermDictionarySurvivesRestart(t *testing.T) {
+	handler, err := NewFileSystemDiskHandler(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create disk handler: %v", err)
+	}
+	config := PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       handler,
+	}
+
+	firstManager := NewPersistenceManager(config)
+	firstManager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("computer", "music", "compute")))
+	firstManager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("computation", "computer")))
+	if err := firstManager.Close(); err != nil {
+		t.Fatalf("Failed to close persistence manager: %v", err)
+	}
+
+	secondManager := NewPersistenceManager(config)
+	defer secondManager.Close()
+
+	terms := data.CollectAsSlice(secondManager.IterateOverDictionary("comput"))
+	if !slices.Equal(terms, []string{"computation", "compute", "computer"}) {
+		t.Errorf("Expected [computation compute computer], got %v", terms)
+	}
+
+	allTerms := data.CollectAsSlice(secondManager.IterateOverDictionary(""))
+	if len(allTerms) != 4 {
+		t.Errorf("Expected 4 terms, got %v", allTerms)
+	}
+}
+
+func TestScoresAfterUpserts(t *testing.T) {
+	manager := NewPersistenceManager(PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       newMockDiskHandler(),
+	})
+	defer manager.Close()
+
+	var appleId, commonId core.DocumentId
+	for range 3 {
+		appleId, _ = manager.UpsertDocument("a.txt", data.NewSliceIterator(UtilTokensFromWords("common", "apple")))
+		commonId, _ = manager.UpsertDocument("b.txt", data.NewSliceIterator(UtilTokensFromWords("common", "pear")))
+	}
+
+	fragments, err1 := search.SplitQuery("common OR apple")
+	query, err2 := search.ParseQuery(fragments)
+	if err1 != nil || err2 != nil {
+		t.Fatalf("Failed to parse query: %v %v", err1, err2)
+	}
+	results := search.NewBoundedResultSet(10)
+	if err := search.Execute(query, manager, results); err != nil {
+		t.Fatalf("Failed to execute query: %v", err)
+	}
+
+	ranked := results.SortedSlice()
+	if len(ranked) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(ranked))
+	}
+	if ranked[0].DocId != appleId || ranked[1].DocId != commonId {
+		t.Errorf("Expected document %d to be ranked before %d, got %d then %d", appleId, commonId, ranked[0].DocId, ranked[1].DocId)
+	}
+	if ranked[1].Score <= 0 {
+		t.Errorf("Expected positive scores, got %f and %f", ranked[0].Score, ranked[1].Score)
+	}
+}
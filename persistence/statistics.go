@@ -0,0 +1,160 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+This file contains the implementation of `core.IndexStatistics` for the
+`PersistenceManager`. The length of every document is stored in a dedicated inverted
+list (the "documents" list), which is made of regular index chunks: every entry is a
+`core.TermTracker` whose position holds the length of the document rather than the
+position of a term. This way the very same chunking, caching and write-back
+machinery used for terms is reused for documents as well. The number of documents
+and the sum of their lengths are persisted by the document commit (see
+document_commit.go), so that they always agree with the documents it publishes,
+while the number of documents containing each term (its document frequency) is
+stored in a small metadata resource of its own. The frequency of a term is increased
+whenever a document containing it is indexed, and decreased when the trackers of a
+deleted document are dropped from its inverted list by a compaction: until then,
+deleted documents still count (they cannot be told apart from the live ones without
+scanning the whole inverted list, which is exactly what the counters avoid).
+The "documents" list is also what allows to enumerate every stored document.
+==================================================================================*/
+
+package persistence
+
+import (
+	"fmt"
+	"iter"
+	"maps"
+	"quinto/core"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+const documentsKey = "docs"
+const statisticsKey = "meta-statistics"
+
+type documentFrequencies struct {
+	mutex  sync.RWMutex
+	counts map[string]uint64
+}
+
+func (df *documentFrequencies) add(term string, delta uint64) {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+	df.counts[term] += delta
+}
+
+func (df *documentFrequencies) subtract(term string, delta uint64) {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+	if df.counts[term] <= delta {
+		delete(df.counts, term)
+		return
+	}
+	df.counts[term] -= delta
+}
+
+func (df *documentFrequencies) get(term string) uint64 {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+	return df.counts[term]
+}
+
+func (df *documentFrequencies) snapshot() map[string]uint64 {
+	df.mutex.RLock()
+	defer df.mutex.RUnlock()
+	return maps.Clone(df.counts)
+}
+
+func (pm *PersistenceManager) loadStatistics() {
+	pm.frequencies.counts = make(map[string]uint64)
+	reader, exists := pm.config.IoHandler.getReader(statisticsKey)
+	if !exists || reader == nil {
+		return
+	}
+	errors := [2]error{}
+	countString, err := decodeStringFromDisk(reader)
+	panicWhenSomeErrorsOccurred([]error{err})
+	count, _ := strconv.Atoi(countString)
+	for range count {
+		var term, frequencyString string
+		term, errors[0] = decodeStringFromDisk(reader)
+		frequencyString, errors[1] = decodeStringFromDisk(reader)
+		panicWhenSomeErrorsOccurred(errors[:])
+		frequency, _ := strconv.ParseUint(frequencyString, 10, 64)
+		pm.frequencies.add(term, frequency)
+	}
+}
+
+func (pm *PersistenceManager) storeStatistics() error {
+	writer, finalize, err := pm.config.IoHandler.getWriter(statisticsKey)
+	if err != nil {
+		return err
+	}
+	frequencies := pm.frequencies.snapshot()
+	if err := encodeStringToDisk(writer, fmt.Sprint(len(frequencies))); err != nil {
+		return err
+	}
+	for _, term := range slices.Sorted(maps.Keys(frequencies)) {
+		if err := encodeStringToDisk(writer, term); err != nil {
+			return err
+		}
+		if err := encodeStringToDisk(writer, fmt.Sprint(frequencies[term])); err != nil {
+			return err
+		}
+	}
+	return finalize()
+}
+
+func (pm *PersistenceManager) recordDocumentLength(docId core.DocumentId, length uint64) {
+	tracker := core.TermTracker{DocId: docId, Position: core.TermPosition(length)}
+	chunk := pm.locateChunk(documentsKey, tracker)
+	chunk.insertIterable(func(yield func(core.TermTracker) bool) { yield(tracker) })
+	pm.markForWriteBack(chunk)
+	chunk.unpin()
+	pm.documentsCount.Add(1)
+	pm.totalLength.Add(length)
+}
+
+func (pm *PersistenceManager) IterateOverDocuments() iter.Seq[core.DocumentId] {
+	return func(yield func(core.DocumentId) bool) {
+		for tracker := range pm.iterateOverChain(documentsKey) {
+			if !yield(tracker.DocId) {
+				return
+			}
+		}
+	}
+}
+
+func (pm *PersistenceManager) DocumentsCount() uint64 {
+	return pm.documentsCount.Load()
+}
+
+func (pm *PersistenceManager) DocumentLength(docId core.DocumentId) uint64 {
+	lastPossibleTracker := core.TermTracker{DocId: docId, Position: ^core.TermPosition(0)}
+	chunk := pm.locateChunk(documentsKey, lastPossibleTracker)
+	defer chunk.unpin()
+	for tracker := range chunk.iterate() {
+		if tracker.DocId == docId {
+			return uint64(tracker.Position)
+		}
+	}
+	return 0
+}
+
+func (pm *PersistenceManager) AverageDocumentLength() float64 {
+	documentsCount := pm.documentsCount.Load()
+	if documentsCount == 0 {
+		return 0
+	}
+	return float64(pm.totalLength.Load()) / float64(documentsCount)
+}
+
+func (pm *PersistenceManager) DocumentFrequency(term string) uint64 {
+	return pm.frequencies.get(term)
+}
@@ -0,0 +1,263 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+This file contains the stored-fields subsystem of the `PersistenceManager`, which
+implements `core.DocumentStore`. Stored documents are grouped in blocks of
+`storedFieldsBlockSize` consecutive document-ids, and every block is persisted as a
+single DEFLATE-compressed resource: compressing many documents together is way more
+effective than compressing them one by one, since documents of the same collection
+share most of their vocabulary. Since document-ids are handed out sequentially, only
+the most recent block is being filled at any given time: it is kept in memory (the
+"open" block), and it is written back whenever a document of another block is
+stored, or together with the rest of the metadata. Loading a document from any
+other block requires to read and decompress the whole block. The (sorted) set of the
+languages of the stored documents is persisted along with the open block.
+==================================================================================*/
+
+package persistence
+
+import (
+	"bufio"
+	"compress/flate"
+	"fmt"
+	"io"
+	"quinto/core"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+const storedFieldsKeyPrefix = "stored-"
+const storedFieldsBlockSize = 32
+const storedLanguagesKey = "meta-stored-languages"
+
+type storedFields struct {
+	mutex          sync.Mutex
+	openBlockIndex uint64
+	openBlock      map[core.DocumentId]core.StoredDocument
+	dirty          bool
+	languages      []string
+	languagesDirty bool
+}
+
+type byteScannerReader struct {
+	io.ByteScanner
+}
+
+func (r byteScannerReader) Read(buffer []byte) (int, error) {
+	for i := range buffer {
+		b, err := r.ReadByte()
+		if err != nil {
+			return i, err
+		}
+		buffer[i] = b
+	}
+	return len(buffer), nil
+}
+
+func storedFieldsBlockKey(blockIndex uint64) string {
+	return storedFieldsKeyPrefix + fmt.Sprint(blockIndex)
+}
+
+func encodeStoredDocument(writer io.Writer, docId core.DocumentId, document core.StoredDocument) error {
+	strings := []string{fmt.Sprint(docId), document.Text, document.Language, fmt.Sprint(len(document.Metadata))}
+	for key, value := range document.Metadata {
+		strings = append(strings, key, value)
+	}
+	for _, s := range strings {
+		if err := encodeStringToDisk(writer, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeStoredDocument(reader io.ByteScanner) (core.DocumentId, core.StoredDocument, error) {
+	errors := [4]error{}
+	var docIdString, metadataCountString string
+	document := core.StoredDocument{Metadata: make(map[string]string)}
+	docIdString, errors[0] = decodeStringFromDisk(reader)
+	document.Text, errors[1] = decodeStringFromDisk(reader)
+	document.Language, errors[2] = decodeStringFromDisk(reader)
+	metadataCountString, errors[3] = decodeStringFromDisk(reader)
+	for _, e := range errors {
+		if e != nil {
+			return 0, document, e
+		}
+	}
+	metadataCount, _ := strconv.Atoi(metadataCountString)
+	for range metadataCount {
+		key, keyErr := decodeStringFromDisk(reader)
+		value, valueErr := decodeStringFromDisk(reader)
+		if keyErr != nil || valueErr != nil {
+			return 0, document, fmt.Errorf("corrupted stored fields: %v %v", keyErr, valueErr)
+		}
+		document.Metadata[key] = value
+	}
+	docId, _ := strconv.ParseUint(docIdString, 10, 64)
+	return core.DocumentId(docId), document, nil
+}
+
+func (pm *PersistenceManager) loadStoredFieldsBlock(blockIndex uint64) (map[core.DocumentId]core.StoredDocument, error) {
+	block := make(map[core.DocumentId]core.StoredDocument)
+	reader, exists := pm.config.IoHandler.getReader(storedFieldsBlockKey(blockIndex))
+	if !exists || reader == nil {
+		return block, nil
+	}
+	decompressor := flate.NewReader(byteScannerReader{reader})
+	defer decompressor.Close()
+	decompressed := bufio.NewReader(decompressor)
+	countString, err := decodeStringFromDisk(decompressed)
+	if err != nil {
+		return nil, err
+	}
+	count, _ := strconv.Atoi(countString)
+	for range count {
+		docId, document, err := decodeStoredDocument(decompressed)
+		if err != nil {
+			return nil, err
+		}
+		block[docId] = document
+	}
+	return block, nil
+}
+
+func (pm *PersistenceManager) storeStoredFieldsBlock(blockIndex uint64, block map[core.DocumentId]core.StoredDocument) error {
+	writer, finalize, err := pm.config.IoHandler.getWriter(storedFieldsBlockKey(blockIndex))
+	if err != nil {
+		return err
+	}
+	compressor, err := flate.NewWriter(writer, flate.DefaultCompression)
+	if err != nil {
+		return err
+	}
+	if err := encodeStringToDisk(compressor, fmt.Sprint(len(block))); err != nil {
+		return err
+	}
+	for docId, document := range block {
+		if err := encodeStoredDocument(compressor, docId, document); err != nil {
+			return err
+		}
+	}
+	if err := compressor.Close(); err != nil {
+		return err
+	}
+	return finalize()
+}
+
+func (pm *PersistenceManager) loadStoredLanguages() {
+	reader, exists := pm.config.IoHandler.getReader(storedLanguagesKey)
+	if !exists || reader == nil {
+		return
+	}
+	countString, err := decodeStringFromDisk(reader)
+	panicWhenSomeErrorsOccurred([]error{err})
+	count, _ := strconv.Atoi(countString)
+	for range count {
+		language, err := decodeStringFromDisk(reader)
+		panicWhenSomeErrorsOccurred([]error{err})
+		pm.stored.languages = append(pm.stored.languages, language)
+	}
+}
+
+func (pm *PersistenceManager) storeStoredLanguages() error {
+	writer, finalize, err := pm.config.IoHandler.getWriter(storedLanguagesKey)
+	if err != nil {
+		return err
+	}
+	if err := encodeStringToDisk(writer, fmt.Sprint(len(pm.stored.languages))); err != nil {
+		return err
+	}
+	for _, language := range pm.stored.languages {
+		if err := encodeStringToDisk(writer, language); err != nil {
+			return err
+		}
+	}
+	return finalize()
+}
+
+func (pm *PersistenceManager) flushStoredFields() error {
+	pm.stored.mutex.Lock()
+	defer pm.stored.mutex.Unlock()
+	if pm.stored.languagesDirty {
+		if err := pm.storeStoredLanguages(); err != nil {
+			return err
+		}
+		pm.stored.languagesDirty = false
+	}
+	return pm.flushOpenStoredFieldsBlock()
+}
+
+func (pm *PersistenceManager) flushOpenStoredFieldsBlock() error {
+	if !pm.stored.dirty {
+		return nil
+	}
+	if err := pm.storeStoredFieldsBlock(pm.stored.openBlockIndex, pm.stored.openBlock); err != nil {
+		return err
+	}
+	pm.stored.dirty = false
+	return nil
+}
+
+func (pm *PersistenceManager) openStoredFieldsBlock(blockIndex uint64) error {
+	if pm.stored.openBlock != nil && pm.stored.openBlockIndex == blockIndex {
+		return nil
+	}
+	if err := pm.flushOpenStoredFieldsBlock(); err != nil {
+		return err
+	}
+	block, err := pm.loadStoredFieldsBlock(blockIndex)
+	if err != nil {
+		return err
+	}
+	pm.stored.openBlockIndex = blockIndex
+	pm.stored.openBlock = block
+	return nil
+}
+
+func (pm *PersistenceManager) StoreDocumentFields(docId core.DocumentId, document core.StoredDocument) error {
+	pm.stored.mutex.Lock()
+	defer pm.stored.mutex.Unlock()
+	if err := pm.openStoredFieldsBlock(uint64(docId) / storedFieldsBlockSize); err != nil {
+		return err
+	}
+	pm.stored.openBlock[docId] = document
+	pm.stored.dirty = true
+	if index, exists := slices.BinarySearch(pm.stored.languages, document.Language); !exists && document.Language != "" {
+		pm.stored.languages = slices.Insert(pm.stored.languages, index, document.Language)
+		pm.stored.languagesDirty = true
+	}
+	pm.storedFieldsSection.pending.Store(true)
+	return nil
+}
+
+func (pm *PersistenceManager) StoredLanguages() []string {
+	pm.stored.mutex.Lock()
+	defer pm.stored.mutex.Unlock()
+	return slices.Clone(pm.stored.languages)
+}
+
+func (pm *PersistenceManager) LoadDocumentFields(docId core.DocumentId) (core.StoredDocument, bool, error) {
+	if pm.isDeleted(docId) {
+		return core.StoredDocument{}, false, nil
+	}
+	blockIndex := uint64(docId) / storedFieldsBlockSize
+	pm.stored.mutex.Lock()
+	if pm.stored.openBlock != nil && pm.stored.openBlockIndex == blockIndex {
+		document, exists := pm.stored.openBlock[docId]
+		pm.stored.mutex.Unlock()
+		return document, exists, nil
+	}
+	pm.stored.mutex.Unlock()
+	block, err := pm.loadStoredFieldsBlock(blockIndex)
+	if err != nil {
+		return core.StoredDocument{}, false, err
+	}
+	document, exists := block[docId]
+	return document, exists, nil
+}
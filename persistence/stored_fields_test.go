@@ -0,0 +1,133 @@
+package persistence
+
+import (
+	"quinto/core"
+	"quinto/data"
+	"slices"
+	"testing"
+)
+
+func TestStoredFieldsAcrossMultipleBlocks(t *testing.T) {
+	manager := NewPersistenceManager(PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       newMockDiskHandler(),
+	})
+	defer manager.Close()
+
+	documentsCount := 3*storedFieldsBlockSize + 1
+	for i := range documentsCount {
+		docId, _ := manager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("guitar")))
+		manager.StoreDocumentFields(docId, core.StoredDocument{
+			Text:     "guitar number " + string(rune('a'+i%26)),
+			Metadata: map[string]string{"position": string(rune('a' + i%26))},
+		})
+	}
+
+	for i := range documentsCount {
+		document, exists, err := manager.LoadDocumentFields(core.DocumentId(i + 1))
+		if err != nil || !exists {
+			t.Fatalf("Expected document %d to be stored, got %v %v", i+1, exists, err)
+		}
+		if expected := "guitar number " + string(rune('a'+i%26)); document.Text != expected {
+			t.Errorf("Expected text %q, got %q", expected, document.Text)
+		}
+		if position := document.Metadata["position"]; position != string(rune('a'+i%26)) {
+			t.Errorf("Expected metadata position %c, got %q", 'a'+i%26, position)
+		}
+	}
+
+	if _, exists, _ := manager.LoadDocumentFields(core.DocumentId(documentsCount + 1)); exists {
+		t.Errorf("Expected a never stored document not to be found")
+	}
+}
+
+func TestStoredFieldsOfDeletedDocuments(t *testing.T) {
+	manager := NewPersistenceManager(PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       newMockDiskHandler(),
+	})
+	defer manager.Close()
+
+	docId, _ := manager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("guitar")))
+	manager.StoreDocumentFields(docId, core.StoredDocument{Text: "guitar"})
+	manager.DeleteDocument(docId)
+
+	if _, exists, _ := manager.LoadDocumentFields(docId); exists {
+		t.Errorf("Expected the stored fields of a deleted document not to be found")
+	}
+}
+
+func TestStoredFieldsSurviveRestart(t *testing.T) {
+	handler, err := NewFileSystemDiskHandler(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create disk handler: %v", err)
+	}
+	config := PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       handler,
+	}
+
+	firstManager := NewPersistenceManager(config)
+	firstId, _ := firstManager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("guitar")))
+	firstManager.StoreDocumentFields(firstId, core.StoredDocument{
+		Text:     "Guitars are string instruments",
+		Metadata: map[string]string{"title": "Guitars", "path": "/docs/guitars.txt"},
+	})
+	if err := firstManager.Close(); err != nil {
+		t.Fatalf("Failed to close persistence manager: %v", err)
+	}
+
+	secondManager := NewPersistenceManager(config)
+	defer secondManager.Close()
+
+	document, exists, err := secondManager.LoadDocumentFields(firstId)
+	if err != nil || !exists {
+		t.Fatalf("Expected document %d to be stored, got %v %v", firstId, exists, err)
+	}
+	if document.Text != "Guitars are string instruments" {
+		t.Errorf("Expected the original text, got %q", document.Text)
+	}
+	if document.Metadata["title"] != "Guitars" || document.Metadata["path"] != "/docs/guitars.txt" {
+		t.Errorf("Expected the original metadata, got %v", document.Metadata)
+	}
+}
+
+func TestStoredLanguagesSurviveRestart(t *testing.T) {
+	handler, err := NewFileSystemDiskHandler(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create disk handler: %v", err)
+	}
+	config := PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       handler,
+	}
+
+	firstManager := NewPersistenceManager(config)
+	for _, document := range []core.StoredDocument{
+		{Text: "chitarra", Language: "ita"},
+		{Text: "guitar", Language: "eng"},
+		{Text: "guitare"},
+		{Text: "chitarre", Language: "ita"},
+	} {
+		docId, _ := firstManager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords(document.Text)))
+		firstManager.StoreDocumentFields(docId, document)
+	}
+	if err := firstManager.Close(); err != nil {
+		t.Fatalf("Failed to close persistence manager: %v", err)
+	}
+
+	secondManager := NewPersistenceManager(config)
+	defer secondManager.Close()
+
+	if languages := secondManager.StoredLanguages(); !slices.Equal(languages, []string{"eng", "ita"}) {
+		t.Errorf("Expected the languages of the stored documents, got %v", languages)
+	}
+	document, exists, err := secondManager.LoadDocumentFields(core.DocumentId(1))
+	if err != nil || !exists || document.Language != "ita" {
+		t.Errorf("Expected the language of the document to be stored, got %q %v %v", document.Language, exists, err)
+	}
+}
@@ -0,0 +1,240 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+This file contains the term dictionary of the `PersistenceManager`: a sorted array
+holding every term that currently has an inverted list. Since chunk keys are derived
+from the terms themselves, the dictionary is the only way to enumerate the terms,
+which is needed to expand prefix, wildcard and fuzzy queries. Being sorted, every
+term that starts with a given prefix can be found with a binary search followed by a
+linear scan. Keeping the array sorted on every insertion would make bulk indexing
+quadratic, so new terms are collected in a set, and merged into the array (sorting
+them once) only when the dictionary is read. The array is never modified in place
+(merging and pruning build a new one), so it can be scanned without holding the lock.
+
+The dictionary is persisted as an append-only log: a base resource, holding a
+generation number followed by the number of terms and the terms themselves, and a
+sequence of segments, each holding only the terms added since the previous one. So
+every write-back only costs as much as the terms it adds. When a compaction empties
+some inverted lists, their terms are pruned from the dictionary, which is then
+rewritten from scratch as a new base with the next generation number: the segments
+of the previous generation are ignored from that moment on, so that the rewrite is
+as atomic as the write of the base itself.
+==================================================================================*/
+
+package persistence
+
+import (
+	"fmt"
+	"io"
+	"iter"
+	"quinto/data"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const termDictionaryKey = "meta-term-dictionary"
+
+type termDictionary struct {
+	mutex      sync.Mutex
+	terms      []string
+	added      data.Set[string]
+	unsaved    []string
+	rewrite    bool
+	generation uint64
+	segments   uint64
+}
+
+func (td *termDictionary) insert(term string) bool {
+	td.mutex.Lock()
+	defer td.mutex.Unlock()
+	if _, exists := slices.BinarySearch(td.terms, term); exists || td.added.Contains(term) {
+		return false
+	}
+	td.added.InsertOne(term)
+	td.unsaved = append(td.unsaved, term)
+	return true
+}
+
+func (td *termDictionary) remove(terms []string) bool {
+	if len(terms) == 0 {
+		return false
+	}
+	td.mutex.Lock()
+	defer td.mutex.Unlock()
+	removed := data.ToSet(terms)
+	td.terms = slices.DeleteFunc(slices.Clone(td.sortedTerms()), removed.Contains)
+	td.rewrite = true
+	return true
+}
+
+func (td *termDictionary) sortedTerms() []string {
+	if td.added.Size() == 0 {
+		return td.terms
+	}
+	added := slices.Sorted(td.added.Iterate())
+	merged := make([]string, 0, len(td.terms)+len(added))
+	i, j := 0, 0
+	for i < len(td.terms) && j < len(added) {
+		if td.terms[i] < added[j] {
+			merged = append(merged, td.terms[i])
+			i++
+		} else {
+			merged = append(merged, added[j])
+			j++
+		}
+	}
+	merged = append(merged, td.terms[i:]...)
+	td.terms = append(merged, added[j:]...)
+	td.added = data.NewSet[string]()
+	return td.terms
+}
+
+func (td *termDictionary) snapshot() []string {
+	td.mutex.Lock()
+	defer td.mutex.Unlock()
+	return slices.Clone(td.sortedTerms())
+}
+
+func (td *termDictionary) iterateWithPrefix(prefix string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		td.mutex.Lock()
+		terms := td.sortedTerms()
+		td.mutex.Unlock()
+		start, _ := slices.BinarySearch(terms, prefix)
+		for _, term := range terms[start:] {
+			if !strings.HasPrefix(term, prefix) || !yield(term) {
+				return
+			}
+		}
+	}
+}
+
+func (td *termDictionary) takeUnsaved() ([]string, bool) {
+	td.mutex.Lock()
+	defer td.mutex.Unlock()
+	unsaved, rewrite := td.unsaved, td.rewrite
+	td.unsaved, td.rewrite = nil, false
+	if rewrite {
+		return slices.Clone(td.sortedTerms()), true
+	}
+	return unsaved, false
+}
+
+func (td *termDictionary) restoreUnsaved(terms []string, rewrite bool) {
+	td.mutex.Lock()
+	defer td.mutex.Unlock()
+	if rewrite {
+		td.rewrite = true
+		return
+	}
+	td.unsaved = append(terms, td.unsaved...)
+}
+
+func dictionarySegmentKey(key string, generation uint64, segment uint64) string {
+	return fmt.Sprintf("%s-%d-%d", key, generation, segment)
+}
+
+func (pm *PersistenceManager) loadTermDictionary() {
+	pm.loadDictionary(termDictionaryKey, &pm.dictionary)
+}
+
+func (pm *PersistenceManager) storeTermDictionary() error {
+	return pm.storeDictionary(termDictionaryKey, &pm.dictionary)
+}
+
+func decodeTerms(reader io.ByteScanner) []string {
+	countString, err := decodeStringFromDisk(reader)
+	panicWhenSomeErrorsOccurred([]error{err})
+	count, _ := strconv.Atoi(countString)
+	terms := make([]string, 0, count)
+	for range count {
+		term, err := decodeStringFromDisk(reader)
+		panicWhenSomeErrorsOccurred([]error{err})
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+func encodeTerms(writer io.Writer, terms []string) error {
+	if err := encodeStringToDisk(writer, fmt.Sprint(len(terms))); err != nil {
+		return err
+	}
+	for _, term := range terms {
+		if err := encodeStringToDisk(writer, term); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pm *PersistenceManager) loadDictionary(key string, dictionary *termDictionary) {
+	dictionary.added = data.NewSet[string]()
+	if reader, exists := pm.config.IoHandler.getReader(key); exists && reader != nil {
+		generationString, err := decodeStringFromDisk(reader)
+		panicWhenSomeErrorsOccurred([]error{err})
+		dictionary.generation, _ = strconv.ParseUint(generationString, 10, 64)
+		dictionary.terms = decodeTerms(reader)
+	}
+	for {
+		segmentKey := dictionarySegmentKey(key, dictionary.generation, dictionary.segments+1)
+		reader, exists := pm.config.IoHandler.getReader(segmentKey)
+		if !exists || reader == nil {
+			return
+		}
+		for _, term := range decodeTerms(reader) {
+			dictionary.added.InsertOne(term)
+		}
+		dictionary.segments++
+	}
+}
+
+func (pm *PersistenceManager) storeDictionary(key string, dictionary *termDictionary) error {
+	terms, rewrite := dictionary.takeUnsaved()
+	if !rewrite && len(terms) == 0 {
+		return nil
+	}
+	if err := pm.writeDictionary(key, dictionary, terms, rewrite); err != nil {
+		dictionary.restoreUnsaved(terms, rewrite)
+		return err
+	}
+	return nil
+}
+
+func (pm *PersistenceManager) writeDictionary(key string, dictionary *termDictionary, terms []string, rewrite bool) error {
+	resourceKey := dictionarySegmentKey(key, dictionary.generation, dictionary.segments+1)
+	if rewrite {
+		resourceKey = key
+	}
+	writer, finalize, err := pm.config.IoHandler.getWriter(resourceKey)
+	if err != nil {
+		return err
+	}
+	if rewrite {
+		if err := encodeStringToDisk(writer, fmt.Sprint(dictionary.generation+1)); err != nil {
+			return err
+		}
+	}
+	if err := encodeTerms(writer, terms); err != nil {
+		return err
+	}
+	if err := finalize(); err != nil {
+		return err
+	}
+	if rewrite {
+		dictionary.generation, dictionary.segments = dictionary.generation+1, 0
+	} else {
+		dictionary.segments++
+	}
+	return nil
+}
+
+func (pm *PersistenceManager) IterateOverDictionary(prefix string) iter.Seq[string] {
+	return pm.dictionary.iterateWithPrefix(prefix)
+}
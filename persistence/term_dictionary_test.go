@@ -0,0 +1,99 @@
+package persistence
+
+import (
+	"quinto/data"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTermDictionaryStaysSortedAcrossInsertions(t *testing.T) {
+	dictionary := termDictionary{added: data.NewSet[string]()}
+	for _, term := range []string{"music", "band", "guitar"} {
+		dictionary.insert(term)
+	}
+	if terms := dictionary.snapshot(); !slices.Equal(terms, []string{"band", "guitar", "music"}) {
+		t.Errorf("Expected [band guitar music], got %v", terms)
+	}
+	for _, term := range []string{"drums", "zither", "accordion", "guitar"} {
+		dictionary.insert(term)
+	}
+	expected := []string{"accordion", "band", "drums", "guitar", "music", "zither"}
+	if terms := dictionary.snapshot(); !slices.Equal(terms, expected) {
+		t.Errorf("Expected %v, got %v", expected, terms)
+	}
+	if terms := data.CollectAsSlice(dictionary.iterateWithPrefix("g")); !slices.Equal(terms, []string{"guitar"}) {
+		t.Errorf("Expected [guitar], got %v", terms)
+	}
+}
+
+func TestTermDictionaryOnlyAppendsNewTerms(t *testing.T) {
+	handler := newMockDiskHandler()
+	config := PersistenceConfig{
+		MaxCachedChunks:   10,
+		MaxChunkSize:      1024,
+		WriteBackInterval: time.Hour,
+		IoHandler:         handler,
+	}
+	manager := NewPersistenceManager(config)
+	defer manager.Close()
+
+	manager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("guitar", "music")))
+	if err := manager.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	manager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("guitar", "drums")))
+	if err := manager.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	if UtilHasBeenWritten(handler, termDictionaryKey) {
+		t.Errorf("Expected the base of the dictionary not to be written without pruning")
+	}
+	if reader, exists := handler.getReader(dictionarySegmentKey(termDictionaryKey, 0, 2)); !exists {
+		t.Errorf("Expected a second segment to be written")
+	} else if terms := decodeTerms(reader); !slices.Equal(terms, []string{"drums"}) {
+		t.Errorf("Expected the second segment to hold only [drums], got %v", terms)
+	}
+
+	reopened := NewPersistenceManager(config)
+	defer reopened.Close()
+	if terms := data.CollectAsSlice(reopened.IterateOverDictionary("")); !slices.Equal(terms, []string{"drums", "guitar", "music"}) {
+		t.Errorf("Expected [drums guitar music], got %v", terms)
+	}
+}
+
+func TestCompactPrunesEmptyTerms(t *testing.T) {
+	handler := newMockDiskHandler()
+	config := PersistenceConfig{
+		MaxCachedChunks:   10,
+		MaxChunkSize:      1024,
+		WriteBackInterval: time.Hour,
+		IoHandler:         handler,
+	}
+	manager := NewPersistenceManager(config)
+
+	guitarId := UtilStoreWords(t, manager, "guitar", "music")
+	UtilStoreWords(t, manager, "drums", "music")
+	if err := manager.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	manager.DeleteDocument(guitarId)
+	manager.Compact()
+	if terms := data.CollectAsSlice(manager.IterateOverDictionary("")); !slices.Equal(terms, []string{"drums", "music"}) {
+		t.Errorf("Expected guitar to be pruned, got %v", terms)
+	}
+	UtilStoreWords(t, manager, "piano")
+	if err := manager.Close(); err != nil {
+		t.Fatalf("Failed to close persistence manager: %v", err)
+	}
+
+	reopened := NewPersistenceManager(config)
+	defer reopened.Close()
+	if terms := data.CollectAsSlice(reopened.IterateOverDictionary("")); !slices.Equal(terms, []string{"drums", "music", "piano"}) {
+		t.Errorf("Expected [drums music piano] after restart, got %v", terms)
+	}
+	if generation := reopened.dictionary.generation; generation != 1 {
+		t.Errorf("Expected the dictionary to be rewritten once, got generation %d", generation)
+	}
+}
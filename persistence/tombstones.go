@@ -0,0 +1,211 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+This file contains the deletion machinery of the `PersistenceManager`. Removing a
+document from every inverted list it appears in would require to rewrite lots of
+chunks at once, so documents are deleted lazily: deleting a document only records a
+tombstone in a bitmap of deleted document-ids, which is persisted as part of the
+document commit. Every iteration over an inverted list skips the tombstoned
+documents, so they immediately disappear from query results and from statistics.
+
+The `core.TermTracker` objects of deleted documents are eventually dropped from the
+index chunks by `Compact`, which is run by the background worker once enough
+documents have been deleted since the last compaction (or explicitly, on demand).
+The terms whose inverted lists end up empty are pruned from their dictionaries.
+A compaction rewrites the chunks of every inverted list, so it excludes every other
+update of the index: updates share the updates lock, while the compaction holds it
+exclusively.
+Tombstones are kept even after compaction: document-ids are never reused, and a
+tombstone only costs a single bit. Documents can also be temporarily hidden (without
+being persisted as tombstones): this is how a new version of a document is kept out
+of sight while it is being indexed, and then swapped with the old version at once.
+Hidden documents never reach the disk: a commit cannot be taken while an update is
+running, and the document-ids of upserts interrupted by a crash are tombstoned when
+the index is opened again.
+==================================================================================*/
+
+package persistence
+
+import (
+	"fmt"
+	"io"
+	"quinto/core"
+	"quinto/data"
+	"strconv"
+	"sync"
+)
+
+const DefaultCompactionThreshold = 1024
+
+type tombstones struct {
+	mutex  sync.RWMutex
+	bitmap *data.Bitmap
+	hidden data.Set[core.DocumentId]
+}
+
+func (ts *tombstones) insert(docId core.DocumentId) bool {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	return ts.bitmap.Insert(uint64(docId))
+}
+
+func (ts *tombstones) contains(docId core.DocumentId) bool {
+	ts.mutex.RLock()
+	defer ts.mutex.RUnlock()
+	return ts.bitmap.Contains(uint64(docId)) || ts.hidden.Contains(docId)
+}
+
+func (ts *tombstones) hide(docId core.DocumentId) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	ts.hidden.InsertOne(docId)
+}
+
+func (ts *tombstones) replace(oldDocId core.DocumentId, newDocId core.DocumentId) bool {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	ts.hidden.Remove(newDocId)
+	return oldDocId != 0 && ts.bitmap.Insert(uint64(oldDocId))
+}
+
+func (ts *tombstones) snapshot() *data.Bitmap {
+	ts.mutex.RLock()
+	defer ts.mutex.RUnlock()
+	words := make([]uint64, len(ts.bitmap.Words()))
+	copy(words, ts.bitmap.Words())
+	return data.NewBitmapFromWords(words)
+}
+
+func decodeTombstones(reader io.ByteScanner) *data.Bitmap {
+	countString, err := decodeStringFromDisk(reader)
+	panicWhenSomeErrorsOccurred([]error{err})
+	count, _ := strconv.Atoi(countString)
+	words := make([]uint64, 0, count)
+	for range count {
+		encoded, err := loadVbyteEncodedUInt64(reader)
+		panicWhenSomeErrorsOccurred([]error{err})
+		words = append(words, vbyteDecodeUInt64(encoded))
+	}
+	return data.NewBitmapFromWords(words)
+}
+
+func encodeTombstones(writer io.Writer, deleted *data.Bitmap) error {
+	words := deleted.Words()
+	if err := encodeStringToDisk(writer, fmt.Sprint(len(words))); err != nil {
+		return err
+	}
+	for _, word := range words {
+		if _, err := writer.Write(vbyteEncodeUInt64(word)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pm *PersistenceManager) DeleteDocument(docId core.DocumentId) error {
+	pm.updatesMutex.RLock()
+	defer pm.updatesMutex.RUnlock()
+	if docId == 0 || uint64(docId) > pm.documentCounter.Load() {
+		return fmt.Errorf("document %d does not exist", docId)
+	}
+	if !pm.deleted.insert(docId) {
+		return fmt.Errorf("document %d has already been deleted", docId)
+	}
+	if pm.externalIds.unbindDocument(docId) {
+		pm.commitPending.Store(true)
+	}
+	pm.forgetDocument(docId)
+	return nil
+}
+
+func (pm *PersistenceManager) discardDocument(docId core.DocumentId) {
+	if pm.deleted.replace(docId, docId) {
+		pm.forgetDocument(docId)
+	}
+}
+
+func (pm *PersistenceManager) forgetDocument(docId core.DocumentId) {
+	length := pm.DocumentLength(docId)
+	pm.documentsCount.Add(^uint64(0))
+	pm.totalLength.Add(^(length - 1))
+	pm.uncompactedDeletions.Add(1)
+	pm.commitPending.Store(true)
+	pm.statisticsSection.pending.Store(true)
+}
+
+func (pm *PersistenceManager) isDeleted(docId core.DocumentId) bool {
+	return pm.deleted.contains(docId)
+}
+
+func (pm *PersistenceManager) compactChain(headKey string, deleted *data.Bitmap) (uint64, bool) {
+	removedDocuments := uint64(0)
+	lastRemovedDocId := core.DocumentId(0)
+	empty := true
+	chunk := pm.retrieveChunk(headKey)
+	defer func() { chunk.unpin() }()
+	for {
+		removedDocIds := chunk.removeDocuments(deleted)
+		for _, docId := range removedDocIds {
+			if docId != lastRemovedDocId {
+				removedDocuments++
+				lastRemovedDocId = docId
+			}
+		}
+		if len(removedDocIds) > 0 {
+			pm.markForWriteBack(chunk)
+		}
+		empty = empty && chunk.isEmpty()
+		nextChunkKey := chunk.next()
+		if nextChunkKey == "" {
+			return removedDocuments, empty
+		}
+		nextChunk := pm.retrieveChunk(nextChunkKey)
+		chunk.unpin()
+		chunk = nextChunk
+	}
+}
+
+func (pm *PersistenceManager) compactDictionary(keyPrefix string, dictionary *termDictionary, deleted *data.Bitmap) (map[string]uint64, bool) {
+	removedDocuments := map[string]uint64{}
+	emptyTerms := []string{}
+	for term := range dictionary.iterateWithPrefix("") {
+		removed, empty := pm.compactChain(keyPrefix+term, deleted)
+		if removed > 0 {
+			removedDocuments[term] = removed
+		}
+		if empty {
+			emptyTerms = append(emptyTerms, term)
+		}
+	}
+	return removedDocuments, dictionary.remove(emptyTerms)
+}
+
+func (pm *PersistenceManager) Compact() {
+	pm.updatesMutex.Lock()
+	defer pm.updatesMutex.Unlock()
+	pm.uncompactedDeletions.Store(0)
+	deleted := pm.deleted.snapshot()
+	if deleted.Size() == 0 {
+		return
+	}
+	removedDocuments, pruned := pm.compactDictionary(termKeyPrefix, &pm.dictionary, deleted)
+	for term, removed := range removedDocuments {
+		pm.frequencies.subtract(term, removed)
+	}
+	if pruned {
+		pm.dictionarySection.pending.Store(true)
+	}
+	if _, pruned := pm.compactDictionary(nGramKeyPrefix, &pm.nGrams, deleted); pruned {
+		pm.nGramsSection.pending.Store(true)
+	}
+	if _, pruned := pm.compactDictionary(fieldKeyPrefix, &pm.fieldTerms, deleted); pruned {
+		pm.fieldTermsSection.pending.Store(true)
+	}
+	pm.compactChain(documentsKey, deleted)
+	pm.statisticsSection.pending.Store(true)
+}
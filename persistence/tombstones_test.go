@@ -0,0 +1,253 @@
+package persistence
+
+import (
+	"quinto/core"
+	"quinto/data"
+	"testing"
+	"time"
+)
+
+func UtilStoreWords(t *testing.T, manager *PersistenceManager, words ...string) core.DocumentId {
+	docId, err := manager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords(words...)))
+	if err != nil {
+		t.Fatalf("Failed to store document: %v", err)
+	}
+	return docId
+}
+
+func TestDeleteDocumentHidesItFromIterations(t *testing.T) {
+	manager := NewPersistenceManager(PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       newMockDiskHandler(),
+	})
+	defer manager.Close()
+
+	first := UtilStoreWords(t, manager, "guitar", "music")
+	second := UtilStoreWords(t, manager, "guitar", "drums", "music", "band")
+	third := UtilStoreWords(t, manager, "guitar")
+
+	if err := manager.DeleteDocument(second); err != nil {
+		t.Fatalf("Failed to delete document: %v", err)
+	}
+
+	guitars := data.CollectAsSlice(manager.IterateOverTerms("guitar"))
+	if len(guitars) != 2 || guitars[0].DocId != first || guitars[1].DocId != third {
+		t.Errorf("Expected guitar trackers for documents %d and %d only, got %v", first, third, guitars)
+	}
+
+	if iters := data.CountIterations(manager.IterateOverTerms("drums")); iters != 0 {
+		t.Errorf("Expected no drums tracker, got %d", iters)
+	}
+
+	if documents := data.CollectAsSlice(manager.IterateOverDocuments()); len(documents) != 2 {
+		t.Errorf("Expected 2 documents, got %v", documents)
+	}
+
+	if count := manager.DocumentsCount(); count != 2 {
+		t.Errorf("Expected 2 documents, got %d", count)
+	}
+
+	if average := manager.AverageDocumentLength(); average != 1.5 {
+		t.Errorf("Expected an average document length of 1.5, got %f", average)
+	}
+
+	if err := manager.DeleteDocument(second); err == nil {
+		t.Errorf("Expected an error when deleting a document twice")
+	}
+
+	if err := manager.DeleteDocument(third + 1); err == nil {
+		t.Errorf("Expected an error when deleting a document that does not exist")
+	}
+}
+
+func TestDeletedDocumentsSurviveRestart(t *testing.T) {
+	handler, err := NewFileSystemDiskHandler(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create disk handler: %v", err)
+	}
+	config := PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       handler,
+	}
+
+	firstManager := NewPersistenceManager(config)
+	deleted := []core.DocumentId{}
+	for i := range 100 {
+		docId := UtilStoreWords(t, firstManager, "guitar")
+		if i%3 == 0 {
+			firstManager.DeleteDocument(docId)
+			deleted = append(deleted, docId)
+		}
+	}
+	if err := firstManager.Close(); err != nil {
+		t.Fatalf("Failed to close persistence manager: %v", err)
+	}
+
+	secondManager := NewPersistenceManager(config)
+	defer secondManager.Close()
+
+	for _, docId := range deleted {
+		if !secondManager.isDeleted(docId) {
+			t.Errorf("Expected document %d to be still deleted", docId)
+		}
+	}
+
+	if iters := data.CountIterations(secondManager.IterateOverTerms("guitar")); iters != 100-len(deleted) {
+		t.Errorf("Expected %d guitar trackers, got %d", 100-len(deleted), iters)
+	}
+}
+
+func TestCompactDropsTrackersOfDeletedDocuments(t *testing.T) {
+	manager := NewPersistenceManager(PersistenceConfig{
+		MaxCachedChunks: 100,
+		MaxChunkSize:    4,
+		IoHandler:       newMockDiskHandler(),
+	})
+	defer manager.Close()
+
+	for i := range 20 {
+		docId := UtilStoreWords(t, manager, "guitar", "music")
+		if i%2 == 0 {
+			manager.DeleteDocument(docId)
+		}
+	}
+
+	manager.Compact()
+
+	for _, headKey := range []string{termKeyPrefix + "guitar", termKeyPrefix + "music", documentsKey} {
+		remaining := 0
+		for chunk := manager.retrieveChunk(headKey); chunk != nil; chunk = manager.retrieveChunk(chunk.nextChunkKey) {
+			for tracker := range chunk.iterate() {
+				remaining++
+				if manager.isDeleted(tracker.DocId) {
+					t.Errorf("Expected tracker of deleted document %d to be dropped from %s", tracker.DocId, headKey)
+				}
+			}
+			if chunk.nextChunkKey == "" {
+				break
+			}
+		}
+		if remaining != 10 {
+			t.Errorf("Expected 10 trackers left in %s, got %d", headKey, remaining)
+		}
+	}
+}
+
+func TestBackgroundWorkerCompactsAfterEnoughDeletions(t *testing.T) {
+	manager := NewPersistenceManager(PersistenceConfig{
+		MaxCachedChunks:     10,
+		MaxChunkSize:        1024,
+		WriteBackInterval:   time.Millisecond,
+		CompactionThreshold: 2,
+		IoHandler:           newMockDiskHandler(),
+	})
+	defer manager.Close()
+
+	first := UtilStoreWords(t, manager, "guitar")
+	second := UtilStoreWords(t, manager, "guitar")
+	UtilStoreWords(t, manager, "guitar")
+	manager.DeleteDocument(first)
+	manager.DeleteDocument(second)
+
+	chunk := manager.retrieveChunk(termKeyPrefix + "guitar")
+	deadline := time.Now().Add(5 * time.Second)
+	for data.CountIterations(chunk.iterate()) != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if iters := data.CountIterations(chunk.iterate()); iters != 1 {
+		t.Errorf("Expected the background worker to compact the chunk down to 1 tracker, got %d", iters)
+	}
+}
+
+func TestCompactionConcurrentWithUpdates(t *testing.T) {
+	manager := NewPersistenceManager(PersistenceConfig{
+		MaxCachedChunks:     4,
+		MaxChunkSize:        4,
+		WriteBackInterval:   time.Millisecond,
+		WriteBackBatchSize:  2,
+		CompactionThreshold: 2,
+		IoHandler:           newMockDiskHandler(),
+	})
+	defer manager.Close()
+
+	words := []string{"guitar", "music", "band", "drums", "bass", "piano"}
+	live := 0
+	for i := range 300 {
+		docId := UtilStoreWords(t, manager, words[i%len(words)], words[(i+1)%len(words)], "song")
+		if i%3 == 0 {
+			if err := manager.DeleteDocument(docId); err != nil {
+				t.Fatalf("Failed to delete document %d: %v", docId, err)
+			}
+			continue
+		}
+		live++
+	}
+	manager.Compact()
+
+	if iters := data.CountIterations(manager.IterateOverTerms("song")); iters != live {
+		t.Errorf("Expected %d live song trackers, got %d", live, iters)
+	}
+	if count := manager.DocumentsCount(); count != uint64(live) {
+		t.Errorf("Expected %d live documents, got %d", live, count)
+	}
+}
+
+func TestNestedIterationsOverTheSameChain(t *testing.T) {
+	manager := NewPersistenceManager(PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       newMockDiskHandler(),
+	})
+	defer manager.Close()
+
+	UtilStoreWords(t, manager, "guitar")
+	UtilStoreWords(t, manager, "guitar")
+
+	pairs := 0
+	for range manager.IterateOverTerms("guitar") {
+		for range manager.IterateOverTerms("guitar") {
+			pairs++
+		}
+	}
+	if pairs != 4 {
+		t.Errorf("Expected 4 pairs of trackers, got %d", pairs)
+	}
+}
+
+func TestCompactUpdatesDocumentFrequencies(t *testing.T) {
+	handler := newMockDiskHandler()
+	config := PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    2,
+		IoHandler:       handler,
+	}
+	manager := NewPersistenceManager(config)
+
+	deletedId := UtilStoreWords(t, manager, "guitar", "music", "guitar", "guitar")
+	UtilStoreWords(t, manager, "guitar", "band")
+	if frequency := manager.DocumentFrequency("guitar"); frequency != 2 {
+		t.Errorf("Expected 'guitar' to appear in 2 documents, got %d", frequency)
+	}
+
+	manager.DeleteDocument(deletedId)
+	manager.Compact()
+	if frequency := manager.DocumentFrequency("guitar"); frequency != 1 {
+		t.Errorf("Expected 'guitar' to appear in 1 document after compaction, got %d", frequency)
+	}
+	if frequency := manager.DocumentFrequency("music"); frequency != 0 {
+		t.Errorf("Expected 'music' to appear in no document after compaction, got %d", frequency)
+	}
+	if err := manager.Close(); err != nil {
+		t.Fatalf("Failed to close persistence manager: %v", err)
+	}
+
+	reopened := NewPersistenceManager(config)
+	defer reopened.Close()
+	reopened.Compact()
+	if frequency := reopened.DocumentFrequency("guitar"); frequency != 1 {
+		t.Errorf("Expected 'guitar' to still appear in 1 document after a restart, got %d", frequency)
+	}
+}
@@ -0,0 +1,212 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+This file contains the write-back machinery of the `PersistenceManager`. Whenever
+an `indexChunk` is modified, its key is pushed into the `pendingSync` queue (only
+once, until the chunk gets written). A background worker wakes up at regular
+intervals, pops a bounded batch of keys from the queue and writes the corresponding
+chunks back to disk (running a compaction first, whenever enough documents have
+been deleted since the last one). This keeps the cost of disk IO out of the write
+path, while guaranteeing that dirty chunks are eventually persisted.
+
+When some document has been stored or deleted since the last document commit (see
+document_commit.go), a batch starts by taking a new one, and remembers how many
+chunks were queued at that time. Every batch also reserves the document-ids handed
+out so far before writing any chunk. The commit is written as soon as every chunk
+queued before it has been written back, which might take a few batches: until then,
+the documents it publishes are not visible on disk. The metadata is written right
+before the commit, so that the stored fields and the dictionaries of the documents
+it publishes are already on disk. Every batch ends by writing back the metadata
+(statistics, stored fields, dictionaries and field boosts) as well, which is split
+into persisted sections: each one has its own dirty flag, raised by the updates that
+touch it, and only the dirty sections are written.
+
+Calling `Flush` forces every chunk that is dirty at the moment of the call to be
+written back, and blocks until that is done. Since the commit held by the worker
+might have been taken long before, once it is written a flush takes (and writes)
+a new one, covering every document stored before the call. Calling `Close` stops the background
+worker and then flushes. A `PersistenceManager` must always be closed, otherwise
+the most recent updates might be lost.
+==================================================================================*/
+
+package persistence
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const DefaultWriteBackInterval = 100 * time.Millisecond
+const DefaultWriteBackBatchSize = 64
+const flushAll = -1
+
+type persistedSection struct {
+	pending atomic.Bool
+	write   func() error
+}
+
+type writeBackWorker struct {
+	batchMutex         sync.Mutex
+	closeOnce          sync.Once
+	stop               chan struct{}
+	done               chan struct{}
+	commit             *documentCommit
+	chunksBeforeCommit int
+}
+
+func (pm *PersistenceManager) startWriteBackWorker() {
+	pm.writeBackWorker.stop = make(chan struct{})
+	pm.writeBackWorker.done = make(chan struct{})
+	go func() {
+		defer close(pm.writeBackWorker.done)
+		ticker := time.NewTicker(pm.config.WriteBackInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-pm.writeBackWorker.stop:
+				return
+			case <-ticker.C:
+				if pm.uncompactedDeletions.Load() >= pm.config.CompactionThreshold {
+					pm.Compact()
+				}
+				pm.writeBackBatch(pm.config.WriteBackBatchSize)
+			}
+		}
+	}()
+}
+
+func (pm *PersistenceManager) markForWriteBack(chunk *indexChunk) {
+	if chunk.pendingWriteBack.Load() && chunk.queuedForSync.CompareAndSwap(false, true) {
+		pm.pendingSync.Push(chunk.chunkKey)
+	}
+}
+
+func (pm *PersistenceManager) writeBackBatch(batchSize int) error {
+	worker := &pm.writeBackWorker
+	worker.batchMutex.Lock()
+	defer worker.batchMutex.Unlock()
+	if err := pm.writeBackTowardsCommit(batchSize); err != nil {
+		return err
+	}
+	if batchSize == flushAll && pm.commitPending.Load() {
+		if err := pm.writeBackTowardsCommit(flushAll); err != nil {
+			return err
+		}
+	}
+	return pm.storeMetadata()
+}
+
+func (pm *PersistenceManager) writeBackTowardsCommit(batchSize int) error {
+	worker := &pm.writeBackWorker
+	if worker.commit == nil && pm.commitPending.Swap(false) {
+		commit, queued := pm.snapshotDocumentCommit()
+		worker.commit, worker.chunksBeforeCommit = &commit, queued
+	}
+	if batchSize == flushAll {
+		batchSize = pm.pendingSync.Size()
+	}
+	written, err := pm.writeBackChunks(batchSize)
+	if err != nil {
+		if worker.commit != nil {
+			worker.commit = nil
+			pm.commitPending.Store(true)
+		}
+		return err
+	}
+	worker.chunksBeforeCommit -= written
+	if worker.commit != nil && worker.chunksBeforeCommit <= 0 {
+		commit := *worker.commit
+		worker.commit = nil
+		if err := pm.storeMetadata(); err != nil {
+			pm.commitPending.Store(true)
+			return err
+		}
+		if err := pm.storeDocumentCommit(commit); err != nil {
+			pm.commitPending.Store(true)
+			return err
+		}
+	}
+	return nil
+}
+
+func (pm *PersistenceManager) writeBackChunks(batchSize int) (int, error) {
+	if err := pm.reserveDocumentIds(); err != nil {
+		return 0, err
+	}
+	popped := 0
+	for ; popped < batchSize; popped++ {
+		key, ok := pm.pendingSync.Pop()
+		if !ok {
+			break
+		}
+		wrappedChunk, exists := pm.chunkPool.Get(key)
+		if !exists {
+			continue
+		}
+		wrappedChunk.chunk.queuedForSync.Store(false)
+		if err := wrappedChunk.chunk.writeBack(); err != nil {
+			pm.markForWriteBack(wrappedChunk.chunk)
+			return popped, err
+		}
+	}
+	return popped, nil
+}
+
+func (section *persistedSection) writeBack() error {
+	if !section.pending.CompareAndSwap(true, false) {
+		return nil
+	}
+	if err := section.write(); err != nil {
+		section.pending.Store(true)
+		return err
+	}
+	return nil
+}
+
+func (pm *PersistenceManager) initMetadataSections() {
+	pm.statisticsSection.write = pm.storeStatistics
+	pm.storedFieldsSection.write = pm.flushStoredFields
+	pm.dictionarySection.write = pm.storeTermDictionary
+	pm.nGramsSection.write = pm.storeNGramDictionary
+	pm.fieldTermsSection.write = pm.storeFieldDictionary
+	pm.fieldBoostsSection.write = pm.storeFieldBoosts
+	pm.metadataSections = []*persistedSection{
+		&pm.statisticsSection,
+		&pm.storedFieldsSection,
+		&pm.dictionarySection,
+		&pm.nGramsSection,
+		&pm.fieldTermsSection,
+		&pm.fieldBoostsSection,
+	}
+}
+
+func (pm *PersistenceManager) storeMetadata() error {
+	for _, section := range pm.metadataSections {
+		if err := section.writeBack(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pm *PersistenceManager) Flush() error {
+	if err := pm.writeBackBatch(flushAll); err != nil {
+		return fmt.Errorf("flush failed: %w", err)
+	}
+	return nil
+}
+
+func (pm *PersistenceManager) Close() error {
+	pm.writeBackWorker.closeOnce.Do(func() {
+		close(pm.writeBackWorker.stop)
+		<-pm.writeBackWorker.done
+	})
+	return pm.Flush()
+}
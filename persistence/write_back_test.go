@@ -0,0 +1,202 @@
+package persistence
+
+import (
+	"fmt"
+	"quinto/data"
+	"sync"
+	"testing"
+	"time"
+)
+
+func UtilHasBeenWritten(handler *mockDiskHandler, key string) bool {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	buffer, exists := handler.mainBuffers[key]
+	return exists && buffer.Len() > 0
+}
+
+func TestFlushPersistsDirtyChunks(t *testing.T) {
+	handler := newMockDiskHandler()
+	config := PersistenceConfig{
+		MaxCachedChunks:   10,
+		MaxChunkSize:      1024,
+		WriteBackInterval: time.Hour,
+		IoHandler:         handler,
+	}
+
+	writerManager := NewPersistenceManager(config)
+	defer writerManager.Close()
+	writerManager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("hello", "world")))
+
+	if UtilHasBeenWritten(handler, "term-hello") {
+		t.Fatalf("Expected chunk not to be written before flushing")
+	}
+
+	if err := writerManager.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	if !writerManager.pendingSync.IsEmpty() {
+		t.Errorf("Expected no pending chunk after flushing")
+	}
+
+	readerManager := NewPersistenceManager(config)
+	defer readerManager.Close()
+	if iters := data.CountIterations(readerManager.IterateOverTerms("hello")); iters != 1 {
+		t.Errorf("Expected 1 hello tracker after flushing, got %d", iters)
+	}
+	if counter := readerManager.documentCounter.Load(); counter != 1 {
+		t.Errorf("Expected document counter to be persisted as 1, got %d", counter)
+	}
+}
+
+func TestBackgroundWorkerPersistsDirtyChunks(t *testing.T) {
+	handler := newMockDiskHandler()
+	manager := NewPersistenceManager(PersistenceConfig{
+		MaxCachedChunks:    10,
+		MaxChunkSize:       1024,
+		WriteBackInterval:  time.Millisecond,
+		WriteBackBatchSize: 1,
+		IoHandler:          handler,
+	})
+	defer manager.Close()
+	manager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("guitar", "music", "band")))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !manager.pendingSync.IsEmpty() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, term := range []string{"guitar", "music", "band"} {
+		if !UtilHasBeenWritten(handler, "term-"+term) {
+			t.Errorf("Expected chunk for %q to be written by the background worker", term)
+		}
+	}
+}
+
+func TestEvictionWhenEveryCachedChunkIsDirty(t *testing.T) {
+	handler := newMockDiskHandler()
+	manager := NewPersistenceManager(PersistenceConfig{
+		MaxCachedChunks:   2,
+		MaxChunkSize:      1024,
+		WriteBackInterval: time.Hour,
+		IoHandler:         handler,
+	})
+
+	words := []string{"one", "two", "three", "four", "five", "six"}
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		manager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords(words...)))
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Storing a document deadlocked while every cached chunk was dirty")
+	}
+
+	if err := manager.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	for _, word := range words {
+		if iters := data.CountIterations(manager.IterateOverTerms(word)); iters != 1 {
+			t.Errorf("Expected 1 tracker for %q, got %d", word, iters)
+		}
+	}
+}
+
+func TestCloseIsIdempotent(t *testing.T) {
+	manager := NewPersistenceManager(PersistenceConfig{
+		MaxCachedChunks: 10,
+		MaxChunkSize:    1024,
+		IoHandler:       newMockDiskHandler(),
+	})
+	if err := manager.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if err := manager.Close(); err != nil {
+		t.Fatalf("Failed to close twice: %v", err)
+	}
+}
+
+func TestCloseCommitsEveryStoredDocument(t *testing.T) {
+	handler := newMockDiskHandler()
+	config := PersistenceConfig{
+		MaxCachedChunks:    4,
+		MaxChunkSize:       1024,
+		WriteBackInterval:  time.Millisecond,
+		WriteBackBatchSize: 2,
+		IoHandler:          handler,
+	}
+
+	writerManager := NewPersistenceManager(config)
+	for i := range 400 {
+		writerManager.StoreNewDocument(data.NewSliceIterator(UtilTokensFromWords("common", fmt.Sprintf("marker%d", i))))
+		if i%50 == 0 {
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	if err := writerManager.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if writerManager.commitPending.Load() {
+		t.Errorf("Expected no pending commit after closing")
+	}
+
+	readerManager := NewPersistenceManager(config)
+	defer readerManager.Close()
+	if iters := data.CountIterations(readerManager.IterateOverTerms("common")); iters != 400 {
+		t.Errorf("Expected 400 documents after reopening, got %d", iters)
+	}
+	if iters := data.CountIterations(readerManager.IterateOverTerms("marker399")); iters != 1 {
+		t.Errorf("Expected the last document to be found after reopening, got %d trackers", iters)
+	}
+	if count := readerManager.DocumentsCount(); count != 400 {
+		t.Errorf("Expected 400 documents to be counted after reopening, got %d", count)
+	}
+}
+
+func TestConcurrentStoresWithSmallCache(t *testing.T) {
+	handler := newMockDiskHandler()
+	config := PersistenceConfig{
+		MaxCachedChunks:    4,
+		MaxChunkSize:       16,
+		WriteBackInterval:  time.Millisecond,
+		WriteBackBatchSize: 2,
+		IoHandler:          handler,
+	}
+
+	writerManager := NewPersistenceManager(config)
+	var wg sync.WaitGroup
+	for worker := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				words := UtilTokensFromWords("common", fmt.Sprintf("worker%d", worker), fmt.Sprintf("marker%d", i%5),
+					fmt.Sprintf("group%d", i%3), fmt.Sprintf("pair%d", (worker+i)%7), "shared")
+				writerManager.StoreNewDocument(data.NewSliceIterator(words))
+			}
+		}()
+	}
+	wg.Wait()
+	if err := writerManager.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	readerManager := NewPersistenceManager(config)
+	defer readerManager.Close()
+	if iters := data.CountIterations(readerManager.IterateOverTerms("common")); iters != 400 {
+		t.Errorf("Expected 400 common trackers after reopening, got %d", iters)
+	}
+	for worker := range 8 {
+		if iters := data.CountIterations(readerManager.IterateOverTerms(fmt.Sprintf("worker%d", worker))); iters != 50 {
+			t.Errorf("Expected 50 trackers of worker %d after reopening, got %d", worker, iters)
+		}
+	}
+	if iters := data.CountIterations(readerManager.IterateOverDocuments()); iters != 400 {
+		t.Errorf("Expected 400 documents after reopening, got %d", iters)
+	}
+}
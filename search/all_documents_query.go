@@ -0,0 +1,77 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+An "AllDocumentsQuery" query is a type of search query that matches every document
+stored in the reverse index, exactly once. It involves no tokens at all, and it is
+intended to be used as the positive side of a unary "NOT" (e.g. "NOT guitar" is
+evaluated as "every document, except the ones matching guitar"). "AllDocumentsQuery"
+implements the Query interface, which defines the "Run", "Advance", and "Close"
+methods. Please refer to the documentation of the "Query" interface for more details
+about its methods and their intended usage.
+==================================================================================*/
+
+package search
+
+import (
+	"iter"
+	"quinto/core"
+	"quinto/data"
+)
+
+type AllDocumentsQuery struct {
+	peek    func() (core.DocumentId, bool)
+	advance func()
+	close   func()
+}
+
+func (q *AllDocumentsQuery) Init(index core.ReverseIndex) {
+	next, stop := iter.Pull(index.IterateOverDocuments())
+	value, exists := next()
+	q.peek = func() (core.DocumentId, bool) {
+		return value, exists
+	}
+	q.advance = func() {
+		value, exists = next()
+	}
+	q.close = stop
+}
+
+func (q *AllDocumentsQuery) Run() core.Match {
+	docId, exists := q.peek()
+	if !exists {
+		return core.Match{Success: false}
+	}
+	return core.Match{
+		Success:        true,
+		DocId:          docId,
+		InvolvedTokens: data.NewSet[core.Token](),
+	}
+}
+
+func (q *AllDocumentsQuery) Advance() {
+	q.advance()
+}
+
+func (q *AllDocumentsQuery) Ended() bool {
+	_, exists := q.peek()
+	return !exists
+}
+
+func (q *AllDocumentsQuery) Close() {
+	if q.close != nil {
+		q.close()
+		q.peek = nil
+		q.advance = nil
+		q.close = nil
+	}
+}
+
+func (q *AllDocumentsQuery) Coordinates() (core.DocumentId, core.TermPosition) {
+	docId, _ := q.peek()
+	return docId, 0
+}
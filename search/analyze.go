@@ -0,0 +1,173 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+This file contains the implementation of the AnalyzeQuery function, which is
+responsible for running the terms of a query through the very same pipeline that
+has been used to index the documents (e.g. lower-casing, stemming). Since the
+reverse index only knows about stemmed terms, a query term must be stemmed before
+it can be looked up. Wildcard patterns are not stemmed, since they are matched
+against the stemmed terms of the dictionary as they are. Terms that are discarded
+by the pipeline (e.g. stop-words) are left untouched, so that the structure of the
+query is preserved. Quoted phrases are run through the pipeline as a whole: since
+stop-words are dropped without advancing positions, the surviving stemmed terms
+line up with the consecutive positions the indexer assigned to them. When the
+documents have been indexed with different pipelines (e.g. one per language),
+AnalyzeMultilingualQuery runs every fragment through all of them: the distinct
+results are joined by OR operators in a parenthesized group, so that the query
+matches the documents of every language. AnalyzeFieldedQuery does the same, but the
+operand following a field prefix (e.g. the whole group in "title:(a OR b)") is
+analyzed with the pipelines of that field.
+==================================================================================*/
+
+package search
+
+import (
+	"iter"
+	"quinto/core"
+	"quinto/data"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type QueryAnalyzer func(iter.Seq[string]) iter.Seq[core.Token]
+
+func startsLikeTerm(text string) bool {
+	r, _ := utf8.DecodeRuneInString(text)
+	return unicode.In(r, unicode.Ll, unicode.Lo, unicode.Nd)
+}
+
+func isTermFragment(fragment queryFragment) bool {
+	return startsLikeTerm(fragment.txt) && !isFieldFragment(fragment)
+}
+
+func isFieldFragment(fragment queryFragment) bool {
+	return len(fragment.txt) > 1 && fragment.txt[len(fragment.txt)-1] == ':' && startsLikeTerm(fragment.txt)
+}
+
+func fieldName(fragment queryFragment) string {
+	return fragment.txt[:len(fragment.txt)-1]
+}
+
+func isPhraseFragment(fragment queryFragment) bool {
+	return len(fragment.txt) >= 2 && fragment.txt[0] == '"' && fragment.txt[len(fragment.txt)-1] == '"'
+}
+
+func isSubstringFragment(fragment queryFragment) bool {
+	return len(fragment.txt) >= 2 && fragment.txt[0] == '\'' && fragment.txt[len(fragment.txt)-1] == '\''
+}
+
+func isBoostFragment(fragment queryFragment) bool {
+	return len(fragment.txt) > 1 && fragment.txt[0] == '^'
+}
+
+func phraseWords(fragment queryFragment) []string {
+	return strings.Fields(fragment.txt[1 : len(fragment.txt)-1])
+}
+
+func analyzePhrase(fragment queryFragment, analyzer QueryAnalyzer) queryFragment {
+	stemmedWords := []string{}
+	for token := range analyzer(data.NewSliceIterator(phraseWords(fragment))) {
+		stemmedWords = append(stemmedWords, token.StemmedText)
+	}
+	if len(stemmedWords) > 0 {
+		fragment.txt = `"` + strings.Join(stemmedWords, " ") + `"`
+	}
+	return fragment
+}
+
+func AnalyzeQuery(fragments []queryFragment, analyzer QueryAnalyzer) []queryFragment {
+	analyzed := make([]queryFragment, 0, len(fragments))
+	for _, fragment := range fragments {
+		if isPhraseFragment(fragment) {
+			fragment = analyzePhrase(fragment, analyzer)
+		}
+		if isTermFragment(fragment) && !isWildcardPattern(fragment.txt) {
+			source := data.NewSliceIterator([]string{fragment.txt})
+			for token := range analyzer(source) {
+				fragment.txt = token.StemmedText
+				break
+			}
+		}
+		analyzed = append(analyzed, fragment)
+	}
+	return analyzed
+}
+
+func AnalyzeMultilingualQuery(fragments []queryFragment, analyzers []QueryAnalyzer) []queryFragment {
+	if len(analyzers) == 0 {
+		return fragments
+	}
+	analyzed := make([]queryFragment, 0, len(fragments))
+	for _, fragment := range fragments {
+		alternatives := []queryFragment{}
+		for _, analyzer := range analyzers {
+			alternative := AnalyzeQuery([]queryFragment{fragment}, analyzer)[0]
+			if !slices.Contains(alternatives, alternative) {
+				alternatives = append(alternatives, alternative)
+			}
+		}
+		if len(alternatives) <= 1 {
+			analyzed = append(analyzed, alternatives...)
+			continue
+		}
+		analyzed = append(analyzed, queryFragment{"(", false, 0})
+		for index, alternative := range alternatives {
+			if index > 0 {
+				analyzed = append(analyzed, queryFragment{"OR", false, 0})
+			}
+			analyzed = append(analyzed, alternative)
+		}
+		analyzed = append(analyzed, queryFragment{")", false, 0})
+	}
+	return analyzed
+}
+
+func fieldScopeEnd(fragments []queryFragment, start int) int {
+	depth := 0
+	for index := start; index < len(fragments); index++ {
+		fragment := fragments[index]
+		if fragment.txt == "(" {
+			depth++
+		} else if fragment.txt == ")" {
+			depth--
+		} else if depth == 0 && (fragment.txt == "NOT" || isFieldFragment(fragment)) {
+			continue
+		}
+		if depth <= 0 {
+			return index + 1
+		}
+	}
+	return len(fragments)
+}
+
+func AnalyzeFieldedQuery(
+	fragments []queryFragment,
+	analyzers []QueryAnalyzer,
+	fieldAnalyzers map[string][]QueryAnalyzer,
+) []queryFragment {
+	analyzed := make([]queryFragment, 0, len(fragments))
+	start := 0
+	for index := 0; index < len(fragments); {
+		if !isFieldFragment(fragments[index]) {
+			index++
+			continue
+		}
+		analyzed = append(analyzed, AnalyzeMultilingualQuery(fragments[start:index], analyzers)...)
+		analyzed = append(analyzed, fragments[index])
+		scopedAnalyzers, exists := fieldAnalyzers[fieldName(fragments[index])]
+		if !exists {
+			scopedAnalyzers = analyzers
+		}
+		end := fieldScopeEnd(fragments, index+1)
+		analyzed = append(analyzed, AnalyzeFieldedQuery(fragments[index+1:end], scopedAnalyzers, fieldAnalyzers)...)
+		start, index = end, end
+	}
+	return append(analyzed, AnalyzeMultilingualQuery(fragments[start:], analyzers)...)
+}
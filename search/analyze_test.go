@@ -0,0 +1,159 @@
+package search
+
+import (
+	"iter"
+	"quinto/core"
+	"strings"
+	"testing"
+)
+
+func pluralTrimmingAnalyzer(source iter.Seq[string]) iter.Seq[core.Token] {
+	return func(yield func(core.Token) bool) {
+		for text := range source {
+			if text == "the" {
+				continue
+			}
+			if !yield(core.Token{StemmedText: strings.TrimSuffix(text, "s"), OriginalText: text}) {
+				return
+			}
+		}
+	}
+}
+
+func TestAnalyzeQuery(t *testing.T) {
+	fragments, err := SplitQuery("guitars AND (the NEAR:ORD:3 bands)")
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+
+	analyzed := AnalyzeQuery(fragments, pluralTrimmingAnalyzer)
+	expected := []queryFragment{
+		{"guitar", false, 0},
+		{"AND", false, 0},
+		{"(", false, 0},
+		{"the", false, 0},
+		{"NEAR", true, 3},
+		{"band", false, 0},
+		{")", false, 0},
+	}
+
+	if len(analyzed) != len(expected) {
+		t.Fatalf("Expected %d fragments, got %d", len(expected), len(analyzed))
+	}
+
+	for i := range expected {
+		if analyzed[i] != expected[i] {
+			t.Errorf("Expected fragment %v, got %v", expected[i], analyzed[i])
+		}
+	}
+}
+
+func TestAnalyzeNonAsciiQuery(t *testing.T) {
+	fragments, err := SplitQuery("élans OR überbands OR 日本s")
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+
+	analyzed := AnalyzeQuery(fragments, pluralTrimmingAnalyzer)
+	expected := []string{"élan", "OR", "überband", "OR", "日本"}
+	if len(analyzed) != len(expected) {
+		t.Fatalf("Expected %d fragments, got %d", len(expected), len(analyzed))
+	}
+
+	for i := range expected {
+		if analyzed[i].txt != expected[i] {
+			t.Errorf("Expected fragment '%s', got '%s'", expected[i], analyzed[i].txt)
+		}
+	}
+}
+
+func TestAnalyzeQueryWithDigits(t *testing.T) {
+	fragments, err := SplitQuery("2024s OR mp3s")
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+
+	analyzed := AnalyzeQuery(fragments, pluralTrimmingAnalyzer)
+	expected := []string{"2024", "OR", "mp3"}
+	if len(analyzed) != len(expected) {
+		t.Fatalf("Expected %d fragments, got %d", len(expected), len(analyzed))
+	}
+
+	for i := range expected {
+		if analyzed[i].txt != expected[i] {
+			t.Errorf("Expected fragment '%s', got '%s'", expected[i], analyzed[i].txt)
+		}
+	}
+}
+
+func TestAnalyzePhraseQuery(t *testing.T) {
+	fragments, err := SplitQuery(`"the guitars of the bands" OR "the"`)
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+
+	analyzed := AnalyzeQuery(fragments, pluralTrimmingAnalyzer)
+	expected := []queryFragment{
+		{`"guitar of band"`, false, 0},
+		{"OR", false, 0},
+		{`"the"`, false, 0},
+	}
+
+	if len(analyzed) != len(expected) {
+		t.Fatalf("Expected %d fragments, got %d", len(expected), len(analyzed))
+	}
+
+	for i := range expected {
+		if analyzed[i] != expected[i] {
+			t.Errorf("Expected fragment %v, got %v", expected[i], analyzed[i])
+		}
+	}
+}
+
+func vowelTrimmingAnalyzer(source iter.Seq[string]) iter.Seq[core.Token] {
+	return func(yield func(core.Token) bool) {
+		for text := range source {
+			if !yield(core.Token{StemmedText: strings.TrimRight(text, "aeiou"), OriginalText: text}) {
+				return
+			}
+		}
+	}
+}
+
+func TestAnalyzeMultilingualQuery(t *testing.T) {
+	fragments, err := SplitQuery(`chitarras AND band NEAR:3 "the pianos"`)
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+
+	analyzed := AnalyzeMultilingualQuery(fragments, []QueryAnalyzer{pluralTrimmingAnalyzer, vowelTrimmingAnalyzer})
+	expected := []queryFragment{
+		{"(", false, 0},
+		{"chitarra", false, 0},
+		{"OR", false, 0},
+		{"chitarras", false, 0},
+		{")", false, 0},
+		{"AND", false, 0},
+		{"band", false, 0},
+		{"NEAR", false, 3},
+		{"(", false, 0},
+		{`"piano"`, false, 0},
+		{"OR", false, 0},
+		{`"th pianos"`, false, 0},
+		{")", false, 0},
+	}
+
+	if len(analyzed) != len(expected) {
+		t.Fatalf("Expected %d fragments, got %d: %v", len(expected), len(analyzed), analyzed)
+	}
+
+	for i := range expected {
+		if analyzed[i] != expected[i] {
+			t.Errorf("Expected fragment %v, got %v", expected[i], analyzed[i])
+		}
+	}
+
+	if _, err := ParseQuery(analyzed); err != nil {
+		t.Errorf("Expected the analyzed query to be parsable, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+This file contains the "Scorer" interface, which assigns a relevance score to a
+"SearchResult", and its main implementation: "BM25Scorer". BM25 (Okapi Best Match 25)
+is a bag-of-words ranking function. For every term involved in the result, it
+weights the frequency of the term in the document (saturated by the "k1" parameter
+and normalized by the length of the document relative to the average one, to an
+extent controlled by the "b" parameter) by the inverse document frequency of the
+term (terms that appear in few documents are more informative):
+
+    score(D) = SUM over t of: idf(t) * tf(t,D) * (k1 + 1) / (tf(t,D) + k1 * norm(D))
+    norm(D)  = 1 - b + b * |D| / avgdl
+    idf(t)   = ln(1 + (N - df(t) + 0.5) / (df(t) + 0.5))
+
+The term frequencies are computed from the "InvolvedTokens" of the result, while the
+collection statistics come from a "core.IndexStatistics" implementation. The same
+occurrence of a term may be involved several times, with different boosts (e.g. in
+"car OR car^2"): occurrences are told apart by their position, so that each one is
+counted once. An index may keep counting deleted documents in the document
+frequencies for a while (until it gets compacted), so df(t) is capped at N: this
+way the idf never turns negative. The contribution of every term is further scaled
+by the highest weight of its tokens (e.g. fuzzy matches weigh less than exact ones).
+==================================================================================*/
+
+package search
+
+import (
+	"math"
+	"quinto/core"
+)
+
+type Scorer interface {
+	Score(result core.SearchResult) float64
+}
+
+type BM25Config struct {
+	K1 float64
+	B  float64
+}
+
+var DefaultBM25Config = BM25Config{K1: 1.2, B: 0.75}
+
+type BM25Scorer struct {
+	config   BM25Config
+	stats    core.IndexStatistics
+	idfCache map[string]float64
+}
+
+type tokenCountScorer struct{}
+
+func NewBM25Scorer(stats core.IndexStatistics, config BM25Config) *BM25Scorer {
+	return &BM25Scorer{
+		config:   config,
+		stats:    stats,
+		idfCache: make(map[string]float64),
+	}
+}
+
+func (s *BM25Scorer) inverseDocumentFrequency(term string) float64 {
+	if idf, exists := s.idfCache[term]; exists {
+		return idf
+	}
+	documentsCount := float64(s.stats.DocumentsCount())
+	documentFrequency := min(float64(s.stats.DocumentFrequency(term)), documentsCount)
+	idf := math.Log(1 + (documentsCount-documentFrequency+0.5)/(documentFrequency+0.5))
+	s.idfCache[term] = idf
+	return idf
+}
+
+func (s *BM25Scorer) lengthNormalization(docId core.DocumentId) float64 {
+	averageLength := s.stats.AverageDocumentLength()
+	if averageLength == 0 {
+		return 1
+	}
+	documentLength := float64(s.stats.DocumentLength(docId))
+	return 1 - s.config.B + s.config.B*documentLength/averageLength
+}
+
+type termOccurrence struct {
+	term     string
+	position core.TermPosition
+}
+
+func termFrequencies(result core.SearchResult) (map[string]float64, map[string]float64) {
+	occurrences := make(map[termOccurrence]bool)
+	frequencies := make(map[string]float64)
+	weights := make(map[string]float64)
+	for token := range result.InvolvedTokens.Iterate() {
+		occurrence := termOccurrence{term: token.StemmedText, position: token.Position}
+		if !occurrences[occurrence] {
+			occurrences[occurrence] = true
+			frequencies[token.StemmedText]++
+		}
+		weights[token.StemmedText] = max(weights[token.StemmedText], token.Weight())
+	}
+	return frequencies, weights
+}
+
+func (s *BM25Scorer) Score(result core.SearchResult) float64 {
+	normalization := s.lengthNormalization(result.DocId)
+	frequencies, weights := termFrequencies(result)
+	score := 0.0
+	for term, tf := range frequencies {
+		saturation := tf * (s.config.K1 + 1) / (tf + s.config.K1*normalization)
+		score += weights[term] * s.inverseDocumentFrequency(term) * saturation
+	}
+	return score
+}
+
+func (tokenCountScorer) Score(result core.SearchResult) float64 {
+	score := 0.0
+	for token := range result.InvolvedTokens.Iterate() {
+		score += token.Weight()
+	}
+	return score
+}
+
+func defaultScorer(index core.ReverseIndex) Scorer {
+	if stats, ok := index.(core.IndexStatistics); ok {
+		return NewBM25Scorer(stats, DefaultBM25Config)
+	}
+	return tokenCountScorer{}
+}
@@ -0,0 +1,98 @@
+package search
+
+import (
+	"math"
+	"quinto/core"
+	"quinto/data"
+	"testing"
+)
+
+func TestBM25InverseDocumentFrequency(t *testing.T) {
+	scorer := NewBM25Scorer(createExecutionTestIndex(), DefaultBM25Config)
+	expectedInstrumentIdf := math.Log(1 + (4-2+0.5)/(2+0.5))
+	if idf := scorer.inverseDocumentFrequency("instrument"); math.Abs(idf-expectedInstrumentIdf) > 1e-9 {
+		t.Errorf("Expected idf %f for 'instrument', got %f", expectedInstrumentIdf, idf)
+	}
+	if scorer.inverseDocumentFrequency("chess") <= scorer.inverseDocumentFrequency("instrument") {
+		t.Errorf("Expected rarer terms to have a higher idf")
+	}
+}
+
+func TestBM25RanksHigherTermFrequencyFirst(t *testing.T) {
+	results := NewBoundedResultSet(10)
+	err := Execute(parseTestQuery(t, "instrument"), createExecutionTestIndex(), results)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ranked := results.SortedSlice()
+	if len(ranked) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(ranked))
+	}
+	if ranked[0].DocId != 2 || ranked[1].DocId != 4 {
+		t.Errorf("Expected the guitar document to be ranked first, got %d then %d", ranked[0].DocId, ranked[1].DocId)
+	}
+	if ranked[0].Score <= ranked[1].Score || ranked[1].Score <= 0 {
+		t.Errorf("Expected strictly decreasing positive scores, got %f and %f", ranked[0].Score, ranked[1].Score)
+	}
+}
+
+func TestBM25PenalizesLongerDocuments(t *testing.T) {
+	index := NewNaiveReverseIndex()
+	shortId, _ := index.StoreNewDocument(data.NewSliceIterator(createDummyDocument([]string{"chess", "board"})))
+	longId, _ := index.StoreNewDocument(data.NewSliceIterator(createDummyDocument([]string{
+		"chess", "opening", "middlegame", "endgame", "tournament", "clock",
+	})))
+
+	scorer := NewBM25Scorer(index, DefaultBM25Config)
+	score := func(docId core.DocumentId) float64 {
+		result := newSearchResult(docId)
+		result.InvolvedTokens.InsertOne(core.Token{StemmedText: "chess"})
+		return scorer.Score(*result)
+	}
+	if score(shortId) <= score(longId) {
+		t.Errorf("Expected the shorter document to score higher")
+	}
+
+	flatScorer := NewBM25Scorer(index, BM25Config{K1: 1.2, B: 0})
+	flatScore := func(docId core.DocumentId) float64 {
+		result := newSearchResult(docId)
+		result.InvolvedTokens.InsertOne(core.Token{StemmedText: "chess"})
+		return flatScorer.Score(*result)
+	}
+	if math.Abs(flatScore(shortId)-flatScore(longId)) > 1e-9 {
+		t.Errorf("Expected no length normalization when b is 0")
+	}
+}
+
+func TestBM25CountsBoostedOccurrencesOnce(t *testing.T) {
+	scorer := NewBM25Scorer(createExecutionTestIndex(), DefaultBM25Config)
+	score := func(tokens ...core.Token) float64 {
+		result := newSearchResult(2)
+		for _, token := range tokens {
+			result.InvolvedTokens.InsertOne(token)
+		}
+		return scorer.Score(*result)
+	}
+	plain := core.Token{StemmedText: "instrument", Position: 3}
+	boosted := core.Token{StemmedText: "instrument", Position: 3, Boost: 2}
+	if single, both := score(boosted), score(plain, boosted); math.Abs(single-both) > 1e-9 {
+		t.Errorf("Expected the boosted occurrence to be counted once (%f), got %f", single, both)
+	}
+	if math.Abs(score(boosted)-2*score(plain)) > 1e-9 {
+		t.Errorf("Expected the boost to be applied once, got %f for %f", score(boosted), score(plain))
+	}
+	if score(plain, core.Token{StemmedText: "instrument", Position: 7}) <= score(plain) {
+		t.Errorf("Expected distinct occurrences to raise the term frequency")
+	}
+}
+
+func TestBoundedResultSetKeepsMostRelevant(t *testing.T) {
+	results := NewBoundedResultSet(2)
+	for docId, score := range []float64{0.5, 3, 1, 2} {
+		results.StoreNewResult(core.SearchResult{DocId: core.DocumentId(docId), Score: score})
+	}
+	ranked := results.SortedSlice()
+	if len(ranked) != 2 || ranked[0].Score != 3 || ranked[1].Score != 2 {
+		t.Errorf("Expected the two most relevant results in order, got %v", ranked)
+	}
+}
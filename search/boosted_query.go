@@ -0,0 +1,72 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+A "BoostedQuery" query wraps another query, and scales the weight of every token
+involved in its matches by a constant factor. The weight of a token determines how
+much it contributes to the score of a document, so a factor greater than one makes
+the wrapped query more relevant than its siblings, while a factor smaller than one
+makes it less relevant (e.g. fuzzy matches are penalized this way, according to
+their edit distance, or explicitly, with the "^" syntax, e.g. "guitar^2.5"). Boosts
+multiply: a boosted query within a boosted query gets the product of the two.
+"BoostedQuery" implements the Query interface, which defines
+the "Run", "Advance", and "Close" methods. Please refer to the documentation of the
+"Query" interface for more details about its methods and their intended usage.
+==================================================================================*/
+
+package search
+
+import (
+	"quinto/core"
+	"quinto/data"
+)
+
+type BoostedQuery struct {
+	query core.Query
+	boost float64
+}
+
+func NewBoostedQuery(query core.Query, boost float64) *BoostedQuery {
+	return &BoostedQuery{query: query, boost: boost}
+}
+
+func (q *BoostedQuery) Init(index core.ReverseIndex) {
+	q.query.Init(index)
+}
+
+func boostMatch(match core.Match, boost float64) core.Match {
+	if !match.Success || boost == 1 {
+		return match
+	}
+	boostedTokens := data.NewSet[core.Token]()
+	for token := range match.InvolvedTokens.Iterate() {
+		token.Boost = token.Weight() * boost
+		boostedTokens.InsertOne(token)
+	}
+	match.InvolvedTokens = boostedTokens
+	return match
+}
+
+func (q *BoostedQuery) Run() core.Match {
+	return boostMatch(q.query.Run(), q.boost)
+}
+
+func (q *BoostedQuery) Advance() {
+	q.query.Advance()
+}
+
+func (q *BoostedQuery) Ended() bool {
+	return q.query.Ended()
+}
+
+func (q *BoostedQuery) Close() {
+	q.query.Close()
+}
+
+func (q *BoostedQuery) Coordinates() (core.DocumentId, core.TermPosition) {
+	return q.query.Coordinates()
+}
@@ -10,8 +10,10 @@ This file contains the implementation of "BoundedResultSet", which is a concrete
 implementation of the "ResultSet" interface, based on a heap data structure.
 
 It is designed to store a limited number of search results, and when the limit is
-reached, it will remove the least relevant result. The results are stored in a
-heap, which allows for efficient insertion and removal of elements.
+reached, it will remove the least relevant result (the one with the lowest score,
+ties are broken in favour of the lowest document-id). The results are stored in a
+min-heap, which allows for efficient insertion and removal of elements. Results are
+returned from the most relevant to the least relevant one.
 ==================================================================================*/
 
 package search
@@ -28,7 +30,7 @@ type BoundedResultSet struct {
 }
 
 func compareResults(a, b core.SearchResult) bool {
-	return a.DocId <= b.DocId
+	return a.Score < b.Score || (a.Score == b.Score && a.DocId > b.DocId)
 }
 
 func NewBoundedResultSet(maxSize int) *BoundedResultSet {
@@ -45,7 +47,7 @@ func (brs *BoundedResultSet) StoreNewResult(result core.SearchResult) {
 	}
 }
 
-func (brs *BoundedResultSet) ToSortedSlice() []core.SearchResult {
+func (brs *BoundedResultSet) SortedSlice() []core.SearchResult {
 	var result = make([]core.SearchResult, brs.storage.Size())
 	originalSize := brs.storage.Size()
 	newStorage := data.NewHeap(compareResults)
@@ -59,5 +61,5 @@ func (brs *BoundedResultSet) ToSortedSlice() []core.SearchResult {
 }
 
 func (brs *BoundedResultSet) Iterate() iter.Seq[core.SearchResult] {
-	return data.NewSliceIterator(brs.ToSortedSlice())
+	return data.NewSliceIterator(brs.SortedSlice())
 }
@@ -12,9 +12,11 @@ that defines wether the two match results of the two queries at hand should resu
 in a match or not. If you want to enforce the scenario in which matches from
 the two queries are required to be ordered such that the first match must refer
 to a term that appears before the second match in the document, you can set the
-"ord" field to true. "ComplexQuery" implements the Query interface, which defines the
-"Run", "Advance", and "Close" methods. Please refer to the documentation of the
-"Query" interface for more details about its methods and their intended usage.
+"ord" field to true. The "operator" field is only descriptive (e.g. "NEAR:ORD:3"),
+and is used to explain the query. "ComplexQuery" implements the Query interface,
+which defines the "Run", "Advance", and "Close" methods. Please refer to the
+documentation of the "Query" interface for more details about its methods and their
+intended usage.
 ==================================================================================*/
 
 package search
@@ -24,10 +26,11 @@ import (
 )
 
 type ComplexQuery struct {
-	lx     core.Query
-	rx     core.Query
-	ord    bool
-	policy func(core.Match, core.Match) bool
+	lx       core.Query
+	rx       core.Query
+	ord      bool
+	policy   func(core.Match, core.Match) bool
+	operator string
 }
 
 var (
@@ -65,7 +68,7 @@ func (q *ComplexQuery) Run() core.Match {
 	if lxMatch.Success && rxMatch.Success {
 
 		if lxMatch.DocId != rxMatch.DocId {
-			return core.Match{Success: false}
+			return q.runLaggingSideOnly(lxMatch, rxMatch)
 		}
 
 		success := true
@@ -95,20 +98,34 @@ func (q *ComplexQuery) Run() core.Match {
 	return core.Match{Success: true}
 }
 
-func (q *ComplexQuery) Advance() {
+func (q *ComplexQuery) runLaggingSideOnly(lxMatch, rxMatch core.Match) core.Match {
+	noMatch := core.Match{Success: false}
+	if lxMatch.DocId < rxMatch.DocId && q.policy(lxMatch, noMatch) {
+		return lxMatch
+	}
+	if rxMatch.DocId < lxMatch.DocId && q.policy(noMatch, rxMatch) {
+		return rxMatch
+	}
+	return noMatch
+}
+
+func (q *ComplexQuery) lxComesFirst() bool {
 	lxDocumentId, lxPosition := q.lx.Coordinates()
 	rxDocumentId, rxPosition := q.rx.Coordinates()
 	shouldGoLxByDocumentId := lxDocumentId < rxDocumentId
 	shouldGoLxByPosition := lxDocumentId == rxDocumentId && lxPosition < rxPosition
-	shouldGoLx := shouldGoLxByDocumentId || shouldGoLxByPosition
-	if shouldGoLx && !q.lx.Ended() {
-		q.lx.Advance()
-	}
-	if !shouldGoLx || q.lx.Ended() {
-		q.rx.Advance()
+	return shouldGoLxByDocumentId || shouldGoLxByPosition
+}
+
+func (q *ComplexQuery) Advance() {
+	lxEnded, rxEnded := q.lx.Ended(), q.rx.Ended()
+	if lxEnded && rxEnded {
+		return
 	}
-	if !shouldGoLx && !q.lx.Ended() && q.rx.Ended() {
+	if rxEnded || (!lxEnded && q.lxComesFirst()) {
 		q.lx.Advance()
+	} else {
+		q.rx.Advance()
 	}
 }
 
@@ -122,13 +139,8 @@ func (q *ComplexQuery) Close() {
 }
 
 func (q *ComplexQuery) Coordinates() (core.DocumentId, core.TermPosition) {
-	lxDocumentId, lxPosition := q.lx.Coordinates()
-	rxDocumentId, rxPosition := q.rx.Coordinates()
-	if lxDocumentId < rxDocumentId {
-		return lxDocumentId, lxPosition
-	}
-	if lxDocumentId == rxDocumentId && lxPosition < rxPosition {
-		return lxDocumentId, lxPosition
+	if q.rx.Ended() || (!q.lx.Ended() && q.lxComesFirst()) {
+		return q.lx.Coordinates()
 	}
-	return rxDocumentId, rxPosition
+	return q.rx.Coordinates()
 }
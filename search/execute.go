@@ -0,0 +1,91 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+This file contains the query executor, which is the driver that turns a "Query"
+into a bunch of "SearchResult" stored in a "ResultSet". It takes care of the whole
+life-cycle of the query: it initializes the query over a reverse index, runs and
+advances it until it ends, and finally closes it. Since a single document can
+produce many matches, all the matches referring to the same document are merged
+into a single "SearchResult" (whose "InvolvedTokens" is the union of the ones of
+every match). Every "SearchResult" is then scored (by default using BM25, whenever
+the reverse index provides the needed statistics) and inserted in the "ResultSet".
+
+The execution can be terminated early, either by cancelling the given context, or
+by setting a maximum number of documents to be collected. In both cases the results
+collected so far are still stored in the "ResultSet".
+==================================================================================*/
+
+package search
+
+import (
+	"context"
+	"quinto/core"
+	"quinto/data"
+)
+
+type ExecutionConfig struct {
+	MaxDocuments int
+	Scorer       Scorer
+}
+
+func Execute(query core.Query, index core.ReverseIndex, rs core.ResultSet) error {
+	return ExecuteContext(context.Background(), query, index, rs, ExecutionConfig{})
+}
+
+func ExecuteContext(
+	ctx context.Context,
+	query core.Query,
+	index core.ReverseIndex,
+	rs core.ResultSet,
+	config ExecutionConfig,
+) error {
+	query.Init(index)
+	defer query.Close()
+
+	scorer := config.Scorer
+	if scorer == nil {
+		scorer = defaultScorer(index)
+	}
+
+	results := map[core.DocumentId]*core.SearchResult{}
+	order := []core.DocumentId{}
+	defer func() {
+		for _, docId := range order {
+			results[docId].Score = scorer.Score(*results[docId])
+			rs.StoreNewResult(*results[docId])
+		}
+	}()
+
+	for !query.Ended() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if match := query.Run(); match.Success {
+			result, exists := results[match.DocId]
+			if !exists && config.MaxDocuments > 0 && len(order) >= config.MaxDocuments {
+				return nil
+			}
+			if !exists {
+				result = newSearchResult(match.DocId)
+				results[match.DocId] = result
+				order = append(order, match.DocId)
+			}
+			result.InvolvedTokens.InsertAll(&match.InvolvedTokens)
+		}
+		query.Advance()
+	}
+	return nil
+}
+
+func newSearchResult(docId core.DocumentId) *core.SearchResult {
+	return &core.SearchResult{
+		DocId:          docId,
+		Score:          0,
+		InvolvedTokens: data.NewSet[core.Token](),
+	}
+}
@@ -0,0 +1,80 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"quinto/core"
+	"quinto/data"
+	"testing"
+)
+
+func createExecutionTestIndex() *NaiveReverseIndex {
+	index := NewNaiveReverseIndex()
+	index.StoreNewDocument(data.NewSliceIterator(helloWorldDocument))
+	index.StoreNewDocument(data.NewSliceIterator(guitarDocument))
+	index.StoreNewDocument(data.NewSliceIterator(hobbyDocument))
+	index.StoreNewDocument(data.NewSliceIterator(toolsDocument))
+	return index
+}
+
+func parseTestQuery(t *testing.T, queryString string) core.Query {
+	fragments, err1 := SplitQuery(queryString)
+	query, err2 := ParseQuery(fragments)
+	if err1 != nil || err2 != nil {
+		t.Fatalf("Failed to parse query: %v %v", err1, err2)
+	}
+	return query
+}
+
+func TestExecuteMergesMatchesOfTheSameDocument(t *testing.T) {
+	results := NewBoundedResultSet(10)
+	err := Execute(parseTestQuery(t, "instrument"), createExecutionTestIndex(), results)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	resultsByDocument := map[core.DocumentId]core.SearchResult{}
+	for result := range results.Iterate() {
+		if _, exists := resultsByDocument[result.DocId]; exists {
+			t.Errorf("Expected a single result for document %d", result.DocId)
+		}
+		resultsByDocument[result.DocId] = result
+	}
+
+	if len(resultsByDocument) != 2 {
+		t.Fatalf("Expected 2 documents, got %d", len(resultsByDocument))
+	}
+
+	guitarResult := resultsByDocument[2]
+	if size := guitarResult.InvolvedTokens.Size(); size != 2 {
+		t.Errorf("Expected both occurrences of 'instrument' to be merged, got %d tokens", size)
+	}
+}
+
+func TestExecuteWithMaxDocuments(t *testing.T) {
+	results := NewBoundedResultSet(10)
+	query := parseTestQuery(t, "instrument OR music OR hello")
+	config := ExecutionConfig{MaxDocuments: 2}
+	err := ExecuteContext(context.Background(), query, createExecutionTestIndex(), results, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if size := len(results.SortedSlice()); size != 2 {
+		t.Errorf("Expected execution to stop after 2 documents, got %d", size)
+	}
+}
+
+func TestExecuteWithCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := NewBoundedResultSet(10)
+	query := parseTestQuery(t, "instrument")
+	err := ExecuteContext(ctx, query, createExecutionTestIndex(), results, ExecutionConfig{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if size := len(results.SortedSlice()); size != 0 {
+		t.Errorf("Expected no results, got %d", size)
+	}
+}
@@ -0,0 +1,89 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+This file contains the implementation of the ExplainQuery function, which renders a
+query tree as human-readable text, one node per line, each child being indented
+below its parent. It shows how a query string has actually been rewritten before
+being executed (e.g. analyzed terms, expanded synonyms). Chains of the very same
+associative operator (e.g. "a OR b OR c", which the parser builds as nested binary
+queries) are flattened into a single node. Boosts are shown next to the node they
+apply to; the boosts of the fields are only known once the query has been initialized
+over some index.
+==================================================================================*/
+
+package search
+
+import (
+	"fmt"
+	"quinto/core"
+	"quinto/data"
+	"strings"
+)
+
+var associativeOperators = data.SliceToSet([]string{"AND", "OR"})
+
+func flattenOperands(query core.Query, operator string) []core.Query {
+	complexQuery, isComplex := query.(*ComplexQuery)
+	if !isComplex || complexQuery.operator != operator || !associativeOperators.Contains(operator) {
+		return []core.Query{query}
+	}
+	return append(flattenOperands(complexQuery.lx, operator), flattenOperands(complexQuery.rx, operator)...)
+}
+
+func describeQuery(query core.Query) (string, []core.Query) {
+	switch q := query.(type) {
+	case *ComplexQuery:
+		operator := q.operator
+		if operator == "" {
+			operator = "COMPLEX"
+		}
+		return operator, append(flattenOperands(q.lx, q.operator), flattenOperands(q.rx, q.operator)...)
+	case *NotQuery:
+		if _, isUnary := q.lx.(*AllDocumentsQuery); isUnary {
+			return "NOT", []core.Query{q.rx}
+		}
+		return "AND NOT", []core.Query{q.lx, q.rx}
+	case *FieldQuery:
+		if q.boost != 0 && q.boost != 1 {
+			return fmt.Sprintf("FIELD %s ^%g", q.field, q.boost), []core.Query{q.query}
+		}
+		return "FIELD " + q.field, []core.Query{q.query}
+	case *BoostedQuery:
+		return fmt.Sprintf("BOOST ^%g", q.boost), []core.Query{q.query}
+	case *ExactQuery:
+		return fmt.Sprintf("TERM %q", q.term), nil
+	case *PhraseQuery:
+		return fmt.Sprintf("PHRASE %q", strings.Join(q.terms, " ")), nil
+	case *WildcardQuery:
+		return fmt.Sprintf("WILDCARD %q", q.pattern), nil
+	case *SubstringQuery:
+		return fmt.Sprintf("SUBSTRING %q", q.substring), nil
+	case *FuzzyQuery:
+		return fmt.Sprintf("FUZZY %q ~%d", q.term, q.maxDistance), nil
+	case *AllDocumentsQuery:
+		return "ALL", nil
+	default:
+		return fmt.Sprintf("%T", query), nil
+	}
+}
+
+func explainQuery(builder *strings.Builder, query core.Query, depth int) {
+	description, children := describeQuery(query)
+	builder.WriteString(strings.Repeat("  ", depth))
+	builder.WriteString(description)
+	builder.WriteString("\n")
+	for _, child := range children {
+		explainQuery(builder, child, depth+1)
+	}
+}
+
+func ExplainQuery(query core.Query) string {
+	builder := strings.Builder{}
+	explainQuery(&builder, query, 0)
+	return builder.String()
+}
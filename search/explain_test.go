@@ -0,0 +1,68 @@
+package search
+
+import (
+	"quinto/core"
+	"quinto/data"
+	"testing"
+)
+
+func TestExplainQuery(t *testing.T) {
+	fragments, err := SplitQuery(`(guitar OR axe OR bass~1) AND NOT ("rock band" NEAR:ORD:3 dru*)`)
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+	query, err := ParseQuery(fragments)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+
+	expected := "AND NOT\n" +
+		"  OR\n" +
+		"    TERM \"guitar\"\n" +
+		"    TERM \"axe\"\n" +
+		"    FUZZY \"bass\" ~1\n" +
+		"  NEAR:ORD:3\n" +
+		"    PHRASE \"rock band\"\n" +
+		"    WILDCARD \"dru*\"\n"
+	if explanation := ExplainQuery(query); explanation != expected {
+		t.Errorf("Expected explanation:\n%s\ngot:\n%s", expected, explanation)
+	}
+}
+
+func TestExplainBoostedQuery(t *testing.T) {
+	query := NewBoostedQuery(&NotQuery{lx: &AllDocumentsQuery{}, rx: &ExactQuery{term: "guitar"}}, 2.5)
+
+	expected := "BOOST ^2.5\n  NOT\n    TERM \"guitar\"\n"
+	if explanation := ExplainQuery(query); explanation != expected {
+		t.Errorf("Expected explanation:\n%s\ngot:\n%s", expected, explanation)
+	}
+}
+
+func TestExplainQueryBoosts(t *testing.T) {
+	fragments, err := SplitQuery(`guitar^2 OR title:(drums OR bass)^1.5`)
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+	query, err := ParseQuery(fragments)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	index := NewNaiveReverseIndex()
+	index.StoreNewFieldedDocument([]core.DocumentField{
+		{Name: "title", Tokens: data.NewSliceIterator(createDummyDocument([]string{"drums"})), Boost: 3},
+	})
+	query.Init(index)
+	defer query.Close()
+
+	expected := "OR\n" +
+		"  BOOST ^2\n" +
+		"    TERM \"guitar\"\n" +
+		"  FIELD title ^3\n" +
+		"    BOOST ^1.5\n" +
+		"      OR\n" +
+		"        TERM \"drums\"\n" +
+		"        TERM \"bass\"\n"
+	if explanation := ExplainQuery(query); explanation != expected {
+		t.Errorf("Expected explanation:\n%s\ngot:\n%s", expected, explanation)
+	}
+}
@@ -0,0 +1,97 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+A "FieldQuery" query restricts another query (e.g. "title:guitar", or a whole
+sub-tree such as "body:(music NEAR:3 band)") to a single field of the documents.
+Rather than having every kind of query know about fields, the wrapped query is
+initialized over a view of the reverse index, in which the terms (and the
+dictionary) are the ones of the field (see "core.FieldIndex"), while everything else
+(e.g. the documents to iterate over) is left untouched. The matches within the field
+are boosted by the boost the field has been given at index time (see
+"core.FieldIndex"). Queries over an index that does not support fields match
+nothing. Substring queries cannot be wrapped (the parser rejects them), since
+n-grams are only indexed for the body of the documents. "FieldQuery" implements the
+Query interface, which defines the "Run", "Advance", and "Close" methods. Please
+refer to the documentation of the "Query" interface for more details about its
+methods and their intended usage.
+==================================================================================*/
+
+package search
+
+import (
+	"iter"
+	"quinto/core"
+)
+
+type FieldQuery struct {
+	field string
+	query core.Query
+	boost float64
+}
+
+type fieldIndexView struct {
+	core.ReverseIndex
+	field string
+}
+
+func NewFieldQuery(field string, query core.Query) *FieldQuery {
+	return &FieldQuery{field: field, query: query}
+}
+
+func (v *fieldIndexView) IterateOverTerms(term string) iter.Seq[core.TermTracker] {
+	if fieldIndex, isFieldIndex := v.ReverseIndex.(core.FieldIndex); isFieldIndex {
+		return fieldIndex.IterateOverFieldTerms(v.field, term)
+	}
+	return func(yield func(core.TermTracker) bool) {}
+}
+
+func (v *fieldIndexView) IterateOverDictionary(prefix string) iter.Seq[string] {
+	if fieldIndex, isFieldIndex := v.ReverseIndex.(core.FieldIndex); isFieldIndex {
+		return fieldIndex.IterateOverFieldDictionary(v.field, prefix)
+	}
+	return func(yield func(string) bool) {}
+}
+
+func unwrapIndexView(index core.ReverseIndex) core.ReverseIndex {
+	for {
+		view, isView := index.(*fieldIndexView)
+		if !isView {
+			return index
+		}
+		index = view.ReverseIndex
+	}
+}
+
+func (q *FieldQuery) Init(index core.ReverseIndex) {
+	index = unwrapIndexView(index)
+	q.boost = 1
+	if fieldIndex, isFieldIndex := index.(core.FieldIndex); isFieldIndex {
+		q.boost = fieldIndex.FieldBoost(q.field)
+	}
+	q.query.Init(&fieldIndexView{ReverseIndex: index, field: q.field})
+}
+
+func (q *FieldQuery) Run() core.Match {
+	return boostMatch(q.query.Run(), q.boost)
+}
+
+func (q *FieldQuery) Advance() {
+	q.query.Advance()
+}
+
+func (q *FieldQuery) Ended() bool {
+	return q.query.Ended()
+}
+
+func (q *FieldQuery) Close() {
+	q.query.Close()
+}
+
+func (q *FieldQuery) Coordinates() (core.DocumentId, core.TermPosition) {
+	return q.query.Coordinates()
+}
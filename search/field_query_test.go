@@ -0,0 +1,191 @@
+package search
+
+import (
+	"context"
+	"math"
+	"quinto/core"
+	"quinto/data"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newFieldedTestIndex() *NaiveReverseIndex {
+	index := NewNaiveReverseIndex()
+	index.StoreNewFieldedDocument([]core.DocumentField{
+		{Name: "body", Tokens: data.NewSliceIterator(createDummyDocument([]string{"music", "band", "drums"}))},
+		{Name: "title", Tokens: data.NewSliceIterator(createDummyDocument([]string{"guitar"}))},
+	})
+	index.StoreNewFieldedDocument([]core.DocumentField{
+		{Name: "body", Tokens: data.NewSliceIterator(createDummyDocument([]string{"guitar", "music"}))},
+		{Name: "title", Tokens: data.NewSliceIterator(createDummyDocument([]string{"band"}))},
+	})
+	return index
+}
+
+func runFieldedQueryHelper(t *testing.T, index *NaiveReverseIndex, queryString string) []core.DocumentId {
+	fragments, err := SplitQuery(queryString)
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+	query, err := ParseQuery(fragments)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	results := NewBoundedResultSet(100)
+	if err := ExecuteContext(ctx, query, index, results, ExecutionConfig{}); err != nil {
+		t.Fatalf("Failed to execute query: %v", err)
+	}
+	docIds := []core.DocumentId{}
+	for _, result := range results.SortedSlice() {
+		docIds = append(docIds, result.DocId)
+	}
+	slices.Sort(docIds)
+	return docIds
+}
+
+func TestFieldQueryOverMultipleDocuments(t *testing.T) {
+	index := newFieldedTestIndex()
+
+	expectations := map[string][]core.DocumentId{
+		"guitar":       {1, 2},
+		"title:guitar": {1},
+		"body:guitar":  {2},
+		"title:guitar AND body:(music NEAR:3 band)": {1},
+		"title:gui*":     {1},
+		"NOT title:band": {1},
+		"body:(guitar OR drums) AND NOT title:band": {1},
+		`"drums guitar"`:      {},
+		"title:(body:guitar)": {2},
+	}
+	for queryString, expected := range expectations {
+		if docIds := runFieldedQueryHelper(t, index, queryString); !slices.Equal(docIds, expected) {
+			t.Errorf("Expected documents %v for %q, got %v", expected, queryString, docIds)
+		}
+	}
+}
+
+func TestSplitAndParseFieldQuery(t *testing.T) {
+	fragments, err := SplitQuery("title:guitar OR body:(music NEAR:3 band)")
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+	expected := []string{"title:", "guitar", "OR", "body:", "(", "music", "NEAR", "band", ")"}
+	texts := []string{}
+	for _, fragment := range fragments {
+		texts = append(texts, fragment.txt)
+	}
+	if !slices.Equal(texts, expected) {
+		t.Fatalf("Expected fragments %v, got %v", expected, texts)
+	}
+
+	query, err := ParseQuery(fragments)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	expectedTree := "OR\n" +
+		"  FIELD title\n" +
+		"    TERM \"guitar\"\n" +
+		"  FIELD body\n" +
+		"    NEAR:3\n" +
+		"      TERM \"music\"\n" +
+		"      TERM \"band\"\n"
+	if explanation := ExplainQuery(query); explanation != expectedTree {
+		t.Errorf("Expected query tree:\n%s\ngot:\n%s", expectedTree, explanation)
+	}
+}
+
+func TestParseFieldPrefixWithoutOperand(t *testing.T) {
+	for _, invalid := range []string{"title:", "guitar OR title:", "title:body:", "(title:)"} {
+		fragments, err := SplitQuery(invalid)
+		if err != nil {
+			t.Fatalf("Failed to split query %q: %v", invalid, err)
+		}
+		if _, err := ParseQuery(fragments); err == nil {
+			t.Errorf("Expected an error for a field prefix without operand in %q", invalid)
+		}
+	}
+}
+
+func TestAnalyzeFieldedQuery(t *testing.T) {
+	fragments, err := SplitQuery("guitars AND title:(bands OR drums) AND NOT body:chitarras")
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+
+	analyzed := AnalyzeFieldedQuery(fragments, []QueryAnalyzer{pluralTrimmingAnalyzer}, map[string][]QueryAnalyzer{
+		"title": {vowelTrimmingAnalyzer},
+	})
+	expected := []string{
+		"guitar", "AND", "title:", "(", "bands", "OR", "drums", ")",
+		"AND", "NOT", "body:", "chitarra",
+	}
+	texts := []string{}
+	for _, fragment := range analyzed {
+		texts = append(texts, fragment.txt)
+	}
+	if !slices.Equal(texts, expected) {
+		t.Errorf("Expected fragments %v, got %v", expected, texts)
+	}
+}
+
+func runScoredQueryHelper(t *testing.T, index *NaiveReverseIndex, queryString string) map[core.DocumentId]float64 {
+	fragments, err := SplitQuery(queryString)
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+	query, err := ParseQuery(fragments)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+
+	results := NewBoundedResultSet(100)
+	if err := Execute(query, index, results); err != nil {
+		t.Fatalf("Failed to execute query: %v", err)
+	}
+	scores := map[core.DocumentId]float64{}
+	for _, result := range results.SortedSlice() {
+		scores[result.DocId] = result.Score
+	}
+	return scores
+}
+
+func TestQueryBoostScalesScore(t *testing.T) {
+	index := newFieldedTestIndex()
+
+	plain := runScoredQueryHelper(t, index, "band")
+	boosted := runScoredQueryHelper(t, index, "band^2.5")
+	for docId, score := range plain {
+		if math.Abs(boosted[docId]-2.5*score) > 1e-9 {
+			t.Errorf("Expected document %d to score %v, got %v", docId, 2.5*score, boosted[docId])
+		}
+	}
+
+	scores := runScoredQueryHelper(t, index, "(title:guitar)^10 OR body:guitar")
+	if scores[1] <= scores[2] {
+		t.Errorf("Expected the boosted title match to rank first, got %v", scores)
+	}
+}
+
+func TestFieldBoostScalesScore(t *testing.T) {
+	index := newFieldedTestIndex()
+	scores := runScoredQueryHelper(t, index, "title:guitar OR body:guitar")
+	if scores[1] >= scores[2] {
+		t.Fatalf("Expected the shorter document to rank first when no field is boosted, got %v", scores)
+	}
+
+	index.StoreNewFieldedDocument([]core.DocumentField{
+		{Name: "title", Tokens: data.NewSliceIterator(createDummyDocument([]string{"drums"})), Boost: 3},
+	})
+	if boost := index.FieldBoost("title"); boost != 3 {
+		t.Fatalf("Expected the title to be boosted by 3, got %v", boost)
+	}
+	scores = runScoredQueryHelper(t, index, "title:guitar OR body:guitar")
+	if scores[1] <= scores[2] {
+		t.Errorf("Expected the boosted title match to rank first, got %v", scores)
+	}
+}
@@ -0,0 +1,106 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+A "FuzzyQuery" query is a type of search query that matches every term within a
+given edit distance from the searched one (e.g. "guitr~1" matches "guitar"), which
+makes searches tolerant to typos. When initialized, the whole term dictionary of the
+reverse index is walked in order through a Levenshtein automaton. Since the dictionary
+is sorted, consecutive terms share long prefixes, so the states of the automaton
+computed for the previous term are reused. Once a prefix leads to a state whose row
+minimum exceeds the maximum distance, no term starting with it can match: the walk
+skips past every such term with a plain byte comparison, without running the
+automaton on any of them (they are still enumerated, since the dictionary offers no
+way to seek past them). The matching terms are merged by a "termUnionQuery" into a
+disjunction, where every term is boosted by the inverse of one plus its edit
+distance, so that exact matches rank above approximate ones. At most "maxExpansions"
+terms are taken into account (the closest ones). "FuzzyQuery" implements the Query
+interface, which defines the "Run", "Advance", and "Close" methods. Please refer to
+the documentation of the "Query" interface for more details about its methods and
+their intended usage.
+==================================================================================*/
+
+package search
+
+import (
+	"cmp"
+	"quinto/core"
+	"slices"
+	"strings"
+)
+
+const MaxFuzzyDistance = 2
+
+type FuzzyQuery struct {
+	termUnionQuery
+	term          string
+	maxDistance   int
+	maxExpansions int
+}
+
+type fuzzyExpansion struct {
+	term     string
+	distance int
+}
+
+func NewFuzzyQuery(term string, maxDistance int, maxExpansions int) *FuzzyQuery {
+	return &FuzzyQuery{term: term, maxDistance: maxDistance, maxExpansions: maxExpansions}
+}
+
+func commonPrefixLength(a, b []rune) int {
+	length := 0
+	for length < len(a) && length < len(b) && a[length] == b[length] {
+		length++
+	}
+	return length
+}
+
+func (q *FuzzyQuery) expand(index core.ReverseIndex) []fuzzyExpansion {
+	automaton := newLevenshteinAutomaton(q.term, q.maxDistance)
+	states := [][]int{automaton.start()}
+	previous := []rune{}
+	deadPrefix := ""
+	expansions := []fuzzyExpansion{}
+	for term := range index.IterateOverDictionary("") {
+		if deadPrefix != "" && strings.HasPrefix(term, deadPrefix) {
+			continue
+		}
+		deadPrefix = ""
+		runes := []rune(term)
+		reusable := min(commonPrefixLength(previous, runes), len(states)-1)
+		states = states[:reusable+1]
+		for i, char := range runes[reusable:] {
+			state := automaton.step(states[len(states)-1], char)
+			states = append(states, state)
+			if !automaton.canMatch(state) {
+				deadPrefix = string(runes[:reusable+i+1])
+				break
+			}
+		}
+		previous = runes
+		if len(states) == len(runes)+1 && automaton.isMatch(states[len(runes)]) {
+			distance := automaton.distance(states[len(runes)])
+			expansions = append(expansions, fuzzyExpansion{term: term, distance: distance})
+		}
+	}
+	slices.SortStableFunc(expansions, func(a, b fuzzyExpansion) int {
+		return cmp.Compare(a.distance, b.distance)
+	})
+	return expansions[:min(len(expansions), q.maxExpansions)]
+}
+
+func (q *FuzzyQuery) Init(index core.ReverseIndex) {
+	queries := []core.Query{}
+	for _, expansion := range q.expand(index) {
+		var query core.Query = &ExactQuery{term: expansion.term}
+		if expansion.distance > 0 {
+			query = NewBoostedQuery(query, 1/float64(1+expansion.distance))
+		}
+		queries = append(queries, query)
+	}
+	q.init(index, queries)
+}
@@ -0,0 +1,93 @@
+package search
+
+import (
+	"quinto/data"
+	"slices"
+	"testing"
+)
+
+func levenshteinDistanceHelper(a, b string, maxDistance int) (int, bool) {
+	automaton := newLevenshteinAutomaton(a, maxDistance)
+	state := automaton.start()
+	for _, char := range b {
+		state = automaton.step(state, char)
+	}
+	return automaton.distance(state), automaton.isMatch(state)
+}
+
+func TestLevenshteinAutomaton(t *testing.T) {
+	cases := []struct {
+		pattern  string
+		text     string
+		distance int
+	}{
+		{"guitar", "guitar", 0},
+		{"guitar", "guitr", 1},
+		{"guitar", "gitar", 1},
+		{"guitar", "guiter", 1},
+		{"guitar", "guitars", 1},
+		{"guitar", "gutiar", 2},
+		{"kitten", "sitting", 3},
+		{"café", "cafe", 1},
+		{"", "abc", 3},
+	}
+	for _, c := range cases {
+		distance, matches := levenshteinDistanceHelper(c.pattern, c.text, 2)
+		if distance != c.distance {
+			t.Errorf("Expected distance(%q, %q) to be %d, got %d", c.pattern, c.text, c.distance, distance)
+		}
+		if matches != (c.distance <= 2) {
+			t.Errorf("Expected match(%q, %q) to be %v", c.pattern, c.text, c.distance <= 2)
+		}
+	}
+}
+
+func TestFuzzyQueryExpansion(t *testing.T) {
+	index := NewNaiveReverseIndex()
+	index.StoreNewDocument(data.NewSliceIterator(createDummyDocument([]string{
+		"guitar", "guitars", "gitar", "guiltier", "gutter", "guide", "zither",
+	})))
+
+	expansions := NewFuzzyQuery("guitar", 1, DefaultMaxTermExpansions).expand(index)
+	expected := []fuzzyExpansion{{"guitar", 0}, {"gitar", 1}, {"guitars", 1}}
+	if !slices.Equal(expansions, expected) {
+		t.Errorf("Expected %v, got %v", expected, expansions)
+	}
+
+	capped := NewFuzzyQuery("guitar", 2, 2).expand(index)
+	if len(capped) != 2 || capped[0].term != "guitar" {
+		t.Errorf("Expected the closest 2 terms, got %v", capped)
+	}
+}
+
+func TestFuzzyQuerySkipsDeadPrefixes(t *testing.T) {
+	index := NewNaiveReverseIndex()
+	index.StoreNewDocument(data.NewSliceIterator(createDummyDocument([]string{
+		"bacon", "bacons", "baconry", "bald", "band", "bands", "bank", "bx", "bxnd", "zzz",
+	})))
+
+	expansions := NewFuzzyQuery("band", 1, DefaultMaxTermExpansions).expand(index)
+	expected := []fuzzyExpansion{{"band", 0}, {"bald", 1}, {"bands", 1}, {"bank", 1}, {"bxnd", 1}}
+	if !slices.Equal(expansions, expected) {
+		t.Errorf("Expected %v, got %v", expected, expansions)
+	}
+}
+
+func TestFuzzyQueryRanksExactMatchesFirst(t *testing.T) {
+	index := NewNaiveReverseIndex()
+	index.StoreNewDocument(data.NewSliceIterator(createDummyDocument([]string{"gitar", "lesson"})))
+	index.StoreNewDocument(data.NewSliceIterator(createDummyDocument([]string{"guitar", "lesson"})))
+
+	results := NewBoundedResultSet(10)
+	if err := Execute(parseTestQuery(t, "guitar~1"), index, results); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ranked := results.SortedSlice()
+	if len(ranked) != 2 || ranked[0].DocId != 2 || ranked[1].DocId != 1 {
+		t.Errorf("Expected the exact match to be ranked first, got %v", ranked)
+	}
+	if ranked[0].Score <= ranked[1].Score {
+		t.Errorf("Expected the fuzzy match to score less, got %f and %f", ranked[0].Score, ranked[1].Score)
+	}
+}
@@ -0,0 +1,162 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+This file contains the highlighter, which turns the "InvolvedTokens" of a
+"SearchResult" into a bunch of human readable fragments of the original text of the
+document. The original text is tokenized again (with the same pipeline used to index
+it, so that the positions of the tokens are the same), and the offsets of the tokens
+are used to cut the fragments out of the text. Every matched token is a candidate
+center for a fragment: the fragment greedily grows around it until it reaches
+"FragmentSize" bytes, and it is scored by the number of matched tokens it contains.
+The best non-overlapping fragments are returned (best first), with the matched tokens
+wrapped in the configured markers (e.g. ANSI colours for a terminal, or <em> tags for
+HTML), while the rest of the text goes through the "Escape" function of the
+configuration, if any (e.g. HTML escaping, so that the text of the documents can
+never be mistaken for markup). When nothing has been matched (e.g. for a purely
+negative query), the beginning of the document is returned as the only fragment.
+==================================================================================*/
+
+package search
+
+import (
+	"cmp"
+	"html"
+	"iter"
+	"quinto/core"
+	"quinto/data"
+	"slices"
+	"strings"
+)
+
+type HighlightConfig struct {
+	MaxFragments int
+	FragmentSize int
+	PreTag       string
+	PostTag      string
+	Escape       func(string) string
+}
+
+var HtmlHighlightConfig = HighlightConfig{
+	MaxFragments: 3,
+	FragmentSize: 120,
+	PreTag:       "<em>",
+	PostTag:      "</em>",
+	Escape:       html.EscapeString,
+}
+
+var AnsiHighlightConfig = HighlightConfig{
+	MaxFragments: 3,
+	FragmentSize: 120,
+	PreTag:       "\x1b[1;31m",
+	PostTag:      "\x1b[0m",
+}
+
+type Fragment struct {
+	StartOffset int
+	EndOffset   int
+	Score       int
+	Text        string
+}
+
+type fragmentWindow struct {
+	first int
+	last  int
+	score int
+}
+
+func growFragmentWindow(tokens []core.Token, matched data.Set[core.TermPosition], center int, size int) fragmentWindow {
+	window := fragmentWindow{first: center, last: center}
+	for window.first > 0 && tokens[center].EndOffset-tokens[window.first-1].StartOffset <= size/2 {
+		window.first--
+	}
+	for window.last+1 < len(tokens) && tokens[window.last+1].EndOffset-tokens[window.first].StartOffset <= size {
+		window.last++
+	}
+	for i := window.first; i <= window.last; i++ {
+		if matched.Contains(tokens[i].Position) {
+			window.score++
+		}
+	}
+	return window
+}
+
+func selectFragmentWindows(tokens []core.Token, matched data.Set[core.TermPosition], config HighlightConfig) []fragmentWindow {
+	candidates := []fragmentWindow{}
+	for i, token := range tokens {
+		if matched.Contains(token.Position) {
+			candidates = append(candidates, growFragmentWindow(tokens, matched, i, config.FragmentSize))
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b fragmentWindow) int {
+		return cmp.Compare(b.score, a.score)
+	})
+	selected := []fragmentWindow{}
+	for _, candidate := range candidates {
+		overlaps := slices.ContainsFunc(selected, func(window fragmentWindow) bool {
+			return candidate.first <= window.last && window.first <= candidate.last
+		})
+		if !overlaps {
+			selected = append(selected, candidate)
+		}
+		if len(selected) == config.MaxFragments {
+			break
+		}
+	}
+	if len(selected) == 0 && len(tokens) > 0 {
+		selected = append(selected, growFragmentWindow(tokens, matched, 0, config.FragmentSize))
+	}
+	return selected
+}
+
+func renderFragment(text string, tokens []core.Token, matched data.Set[core.TermPosition], window fragmentWindow, config HighlightConfig) Fragment {
+	fragment := Fragment{
+		StartOffset: tokens[window.first].StartOffset,
+		EndOffset:   tokens[window.last].EndOffset,
+		Score:       window.score,
+	}
+	escape := config.Escape
+	if escape == nil {
+		escape = func(text string) string { return text }
+	}
+	builder := strings.Builder{}
+	cursor := fragment.StartOffset
+	for _, token := range tokens[window.first : window.last+1] {
+		if !matched.Contains(token.Position) {
+			continue
+		}
+		builder.WriteString(escape(text[cursor:token.StartOffset]))
+		builder.WriteString(config.PreTag)
+		builder.WriteString(escape(text[token.StartOffset:token.EndOffset]))
+		builder.WriteString(config.PostTag)
+		cursor = token.EndOffset
+	}
+	builder.WriteString(escape(text[cursor:fragment.EndOffset]))
+	fragment.Text = builder.String()
+	return fragment
+}
+
+func Highlight(
+	text string,
+	tokens iter.Seq[core.Token],
+	involvedTokens data.Set[core.Token],
+	config HighlightConfig,
+) []Fragment {
+	if config.MaxFragments <= 0 {
+		config.MaxFragments = 1
+	}
+	matched := data.NewSet[core.TermPosition]()
+	for token := range involvedTokens.Iterate() {
+		matched.InsertOne(token.Position)
+	}
+	collected := data.CollectAsSlice(tokens)
+	fragments := []Fragment{}
+	for _, window := range selectFragmentWindows(collected, matched, config) {
+		fragments = append(fragments, renderFragment(text, collected, matched, window, config))
+	}
+	return fragments
+}
@@ -0,0 +1,110 @@
+package search
+
+import (
+	"iter"
+	"quinto/core"
+	"quinto/data"
+	"strings"
+	"testing"
+)
+
+func highlightTestTokens(text string) iter.Seq[core.Token] {
+	tokens := []core.Token{}
+	for span := range data.NewTextSpanIterator(text) {
+		tokens = append(tokens, core.Token{
+			StemmedText:  strings.ToLower(strings.Trim(span.Text, ".,")),
+			OriginalText: span.Text,
+			Position:     core.TermPosition(len(tokens)),
+			StartOffset:  span.StartOffset,
+			EndOffset:    span.EndOffset,
+		})
+	}
+	return data.NewSliceIterator(tokens)
+}
+
+func highlightTestMatches(positions ...core.TermPosition) data.Set[core.Token] {
+	matches := data.NewSet[core.Token]()
+	for _, position := range positions {
+		matches.InsertOne(core.Token{Position: position})
+	}
+	return matches
+}
+
+func TestHighlightWrapsMatchedTokens(t *testing.T) {
+	text := "Hello world, music of the  world."
+	fragments := Highlight(text, highlightTestTokens(text), highlightTestMatches(1, 5), HtmlHighlightConfig)
+
+	if len(fragments) != 1 {
+		t.Fatalf("Expected 1 fragment, got %d", len(fragments))
+	}
+	if expected := "Hello <em>world,</em> music of the  <em>world.</em>"; fragments[0].Text != expected {
+		t.Errorf("Expected %q, got %q", expected, fragments[0].Text)
+	}
+	if fragments[0].StartOffset != 0 || fragments[0].EndOffset != len(text) {
+		t.Errorf("Expected the fragment to span the whole text, got [%d, %d)", fragments[0].StartOffset, fragments[0].EndOffset)
+	}
+	if fragments[0].Score != 2 {
+		t.Errorf("Expected the fragment to contain 2 matches, got %d", fragments[0].Score)
+	}
+}
+
+func TestHighlightEscapesHtml(t *testing.T) {
+	text := "Use <script> tags with the guitar & drums"
+	fragments := Highlight(text, highlightTestTokens(text), highlightTestMatches(5), HtmlHighlightConfig)
+
+	if len(fragments) != 1 {
+		t.Fatalf("Expected 1 fragment, got %d", len(fragments))
+	}
+	if expected := "Use &lt;script&gt; tags with the <em>guitar</em> &amp; drums"; fragments[0].Text != expected {
+		t.Errorf("Expected %q, got %q", expected, fragments[0].Text)
+	}
+
+	fragments = Highlight(text, highlightTestTokens(text), highlightTestMatches(5), AnsiHighlightConfig)
+	if expected := "Use <script> tags with the \x1b[1;31mguitar\x1b[0m & drums"; fragments[0].Text != expected {
+		t.Errorf("Expected %q, got %q", expected, fragments[0].Text)
+	}
+}
+
+func TestHighlightPrefersDenserFragments(t *testing.T) {
+	filler := strings.Repeat("lorem ipsum dolor sit amet ", 10)
+	text := "guitar " + filler + "guitar music band " + filler + "music"
+	words := strings.Fields(text)
+	matches := []core.TermPosition{}
+	for i, word := range words {
+		if word == "guitar" || word == "music" || word == "band" {
+			matches = append(matches, core.TermPosition(i))
+		}
+	}
+
+	config := HighlightConfig{MaxFragments: 2, FragmentSize: 40, PreTag: "[", PostTag: "]"}
+	fragments := Highlight(text, highlightTestTokens(text), highlightTestMatches(matches...), config)
+
+	if len(fragments) != 2 {
+		t.Fatalf("Expected 2 fragments, got %d", len(fragments))
+	}
+	if !strings.Contains(fragments[0].Text, "[guitar] [music] [band]") || fragments[0].Score != 3 {
+		t.Errorf("Expected the densest fragment first, got %q", fragments[0].Text)
+	}
+	for _, fragment := range fragments {
+		if fragment.EndOffset-fragment.StartOffset > config.FragmentSize {
+			t.Errorf("Expected fragments of at most %d bytes, got %q", config.FragmentSize, text[fragment.StartOffset:fragment.EndOffset])
+		}
+	}
+	if fragments[0].StartOffset < fragments[1].EndOffset && fragments[1].StartOffset < fragments[0].EndOffset {
+		t.Errorf("Expected non-overlapping fragments, got %v", fragments)
+	}
+}
+
+func TestHighlightWithoutMatches(t *testing.T) {
+	text := "guitar string instrument band"
+	config := HighlightConfig{MaxFragments: 3, FragmentSize: 20, PreTag: "[", PostTag: "]"}
+	fragments := Highlight(text, highlightTestTokens(text), data.NewSet[core.Token](), config)
+
+	if len(fragments) != 1 || fragments[0].Text != "guitar string" {
+		t.Errorf("Expected the beginning of the document, got %v", fragments)
+	}
+
+	if fragments := Highlight("", highlightTestTokens(""), data.NewSet[core.Token](), config); len(fragments) != 0 {
+		t.Errorf("Expected no fragments for an empty document, got %v", fragments)
+	}
+}
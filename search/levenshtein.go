@@ -0,0 +1,67 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+This file contains a Levenshtein automaton: an automaton that accepts every string
+whose edit distance (insertions, deletions and substitutions of a single character)
+from a given pattern is at most "maxDistance". The automaton is simulated rather
+than compiled: its state is a row of the classic dynamic-programming matrix, where
+the i-th cell holds the distance between the first i characters of the pattern and
+the characters consumed so far. Feeding a character computes the next row out of
+the current one. Whenever every cell of a row exceeds "maxDistance", no string with
+the consumed prefix can ever be accepted, which allows to prune whole branches of a
+sorted term dictionary at once.
+==================================================================================*/
+
+package search
+
+type levenshteinAutomaton struct {
+	pattern     []rune
+	maxDistance int
+}
+
+func newLevenshteinAutomaton(pattern string, maxDistance int) levenshteinAutomaton {
+	return levenshteinAutomaton{pattern: []rune(pattern), maxDistance: maxDistance}
+}
+
+func (a levenshteinAutomaton) start() []int {
+	state := make([]int, len(a.pattern)+1)
+	for i := range state {
+		state[i] = i
+	}
+	return state
+}
+
+func (a levenshteinAutomaton) step(state []int, char rune) []int {
+	next := make([]int, len(state))
+	next[0] = state[0] + 1
+	for i := 1; i < len(state); i++ {
+		substitutionCost := 1
+		if a.pattern[i-1] == char {
+			substitutionCost = 0
+		}
+		next[i] = min(state[i-1]+substitutionCost, state[i]+1, next[i-1]+1)
+	}
+	return next
+}
+
+func (a levenshteinAutomaton) distance(state []int) int {
+	return state[len(state)-1]
+}
+
+func (a levenshteinAutomaton) isMatch(state []int) bool {
+	return a.distance(state) <= a.maxDistance
+}
+
+func (a levenshteinAutomaton) canMatch(state []int) bool {
+	for _, distance := range state {
+		if distance <= a.maxDistance {
+			return true
+		}
+	}
+	return false
+}
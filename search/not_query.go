@@ -0,0 +1,83 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+A "NotQuery" query is a type of search query that yields the matches of its positive
+side ("lx"), skipping every document in which the negated side ("rx") matches at
+least once. Since both sides are iterated in ascending order of document-id, the
+negated side is lazily advanced up to the document of the current positive match,
+and never rewinded. The verdict for the last inspected document is cached, so that
+many matches in the same document only cost a single check. "a AND NOT b" is
+evaluated as a "NotQuery" whose positive side is "a", while a unary "NOT b" uses an
+"AllDocumentsQuery" as positive side. "NotQuery" implements the Query interface,
+which defines the "Run", "Advance", and "Close" methods. Please refer to the
+documentation of the "Query" interface for more details about its methods and their
+intended usage.
+==================================================================================*/
+
+package search
+
+import (
+	"quinto/core"
+)
+
+type NotQuery struct {
+	lx           core.Query
+	rx           core.Query
+	checked      bool
+	checkedDocId core.DocumentId
+	excluded     bool
+}
+
+func (q *NotQuery) Init(index core.ReverseIndex) {
+	q.lx.Init(index)
+	q.rx.Init(index)
+	q.checked = false
+}
+
+func (q *NotQuery) isExcluded(docId core.DocumentId) bool {
+	if q.checked && q.checkedDocId == docId {
+		return q.excluded
+	}
+	q.checked, q.checkedDocId, q.excluded = true, docId, false
+	for !q.rx.Ended() {
+		if match := q.rx.Run(); match.Success && match.DocId == docId {
+			q.excluded = true
+			break
+		}
+		if rxDocId, _ := q.rx.Coordinates(); rxDocId > docId {
+			break
+		}
+		q.rx.Advance()
+	}
+	return q.excluded
+}
+
+func (q *NotQuery) Run() core.Match {
+	match := q.lx.Run()
+	if !match.Success || q.isExcluded(match.DocId) {
+		return core.Match{Success: false}
+	}
+	return match
+}
+
+func (q *NotQuery) Advance() {
+	q.lx.Advance()
+}
+
+func (q *NotQuery) Ended() bool {
+	return q.lx.Ended()
+}
+
+func (q *NotQuery) Close() {
+	q.lx.Close()
+	q.rx.Close()
+}
+
+func (q *NotQuery) Coordinates() (core.DocumentId, core.TermPosition) {
+	return q.lx.Coordinates()
+}
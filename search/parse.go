@@ -11,7 +11,19 @@ for translating a sequence of fragments into a well-formed query (a tree-like st
 
 The parsing itself is done using a stack-based approach, where operators and operands
 are pushed onto their respective stacks. The precedence of operators is taken into
-account to ensure that the resulting query structure is correct.
+account to ensure that the resulting query structure is correct. "NOT" is the only
+unary (prefix) operator, and it has the highest precedence of all: "NOT a AND b" is
+parsed as "[NOT a] AND b". A "NOT" that directly follows an operand implies an "AND"
+(e.g. "a NOT b" is the same as "a AND NOT b"). Whenever one side of an "AND" is a
+unary negation, the two are folded into a single "NotQuery", so that "a AND NOT b"
+only iterates over the documents matching "a", rather than over every document.
+Field prefixes (e.g. "title:") are unary (prefix) operators as well, binding even
+tighter than "NOT": "title:a OR b" is parsed as "[title:a] OR b". Substrings cannot
+be restricted to a field, since n-grams are only indexed for the body of documents.
+Boosts (e.g. "^2.5") are postfix: they wrap the operand right before them, be it a
+term, a phrase or a parenthesized group, into a "BoostedQuery", so "a^2 OR b" is
+parsed as "[a^2] OR b", and "title:a^2" as "title:[a^2]". A boost cannot follow
+another boost ("a^2^3" is rejected rather than silently multiplied).
 ==================================================================================*/
 
 package search
@@ -19,6 +31,11 @@ package search
 import (
 	"fmt"
 	"quinto/core"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type parsingState struct {
@@ -31,6 +48,10 @@ type openParenthesis struct {
 	openingPosition int
 }
 
+type andOperator struct {
+	ord bool
+}
+
 func stackPush[T any](stack *[]T, q T) {
 	*stack = append(*stack, q)
 }
@@ -45,26 +66,158 @@ func stackPop[T any](stack *[]T) T {
 	return q
 }
 
-func evaluateOne(queryStack *[]core.Query, opStack *[]any) {
+func asUnaryNegation(query core.Query) (*NotQuery, bool) {
+	negation, isNegation := query.(*NotQuery)
+	if !isNegation {
+		return nil, false
+	}
+	_, isUnary := negation.lx.(*AllDocumentsQuery)
+	return negation, isUnary
+}
+
+func evaluateAnd(lx, rx core.Query, ord bool) core.Query {
+	if negation, isUnary := asUnaryNegation(rx); isUnary {
+		return &NotQuery{lx: lx, rx: negation.rx}
+	}
+	if negation, isUnary := asUnaryNegation(lx); isUnary {
+		return &NotQuery{lx: rx, rx: negation.rx}
+	}
+	return &ComplexQuery{lx: lx, rx: rx, ord: ord, policy: AndQueryPolicy, operator: operatorName("AND", ord, 0)}
+}
+
+func operatorName(operator string, ord bool, opt int) string {
+	if ord {
+		operator += ":ORD"
+	}
+	if opt > 0 {
+		operator += fmt.Sprintf(":%d", opt)
+	}
+	return operator
+}
+
+func newComplexOperator(fragment queryFragment, policy func(core.Match, core.Match) bool) any {
+	return ComplexQuery{ord: fragment.ord, policy: policy, operator: operatorName(fragment.txt, fragment.ord, fragment.opt)}
+}
+
+func popOperands(queryStack *[]core.Query, operator string, count int) ([]core.Query, error) {
+	if len(*queryStack) < count {
+		return nil, fmt.Errorf("invalid query: missing operand of %s", operator)
+	}
+	operands := slices.Clone((*queryStack)[len(*queryStack)-count:])
+	*queryStack = (*queryStack)[:len(*queryStack)-count]
+	return operands, nil
+}
+
+func containsSubstringQuery(query core.Query) bool {
+	if _, isSubstring := query.(*SubstringQuery); isSubstring {
+		return true
+	}
+	_, children := describeQuery(query)
+	return slices.ContainsFunc(children, containsSubstringQuery)
+}
+
+func evaluateOne(queryStack *[]core.Query, opStack *[]any) error {
 	op := stackPop(opStack)
 	switch v := op.(type) {
 	case ComplexQuery:
-		v.rx = stackPop(queryStack)
-		v.lx = stackPop(queryStack)
+		operands, err := popOperands(queryStack, v.operator, 2)
+		if err != nil {
+			return err
+		}
+		v.lx, v.rx = operands[0], operands[1]
+		var castedToQuery core.Query = &v
+		stackPush(queryStack, castedToQuery)
+	case andOperator:
+		operands, err := popOperands(queryStack, operatorName("AND", v.ord, 0), 2)
+		if err != nil {
+			return err
+		}
+		stackPush(queryStack, evaluateAnd(operands[0], operands[1], v.ord))
+	case FieldQuery:
+		operands, err := popOperands(queryStack, v.field+":", 1)
+		if err != nil {
+			return err
+		}
+		if containsSubstringQuery(operands[0]) {
+			return fmt.Errorf("invalid query: substrings cannot be restricted to a field (%s:)", v.field)
+		}
+		v.query = operands[0]
+		var castedToQuery core.Query = &v
+		stackPush(queryStack, castedToQuery)
+	case NotQuery:
+		operands, err := popOperands(queryStack, "NOT", 1)
+		if err != nil {
+			return err
+		}
+		v.rx = operands[0]
+		v.lx = &AllDocumentsQuery{}
 		var castedToQuery core.Query = &v
 		stackPush(queryStack, castedToQuery)
-	default:
-		return
 	}
+	return nil
 }
 
-func evaluateAll(state parsingState, currentPrecedence int) {
+func evaluateAll(state parsingState, currentPrecedence int) error {
 	counter := len(*state.precedenceStack) - 1
 	for counter >= 0 && (*state.precedenceStack)[counter] >= currentPrecedence {
-		evaluateOne(state.queryStack, state.opStack)
+		if err := evaluateOne(state.queryStack, state.opStack); err != nil {
+			return err
+		}
 		stackPop(state.precedenceStack)
 		counter--
 	}
+	return nil
+}
+
+func pushAnd(state parsingState, ord bool) error {
+	if err := evaluateAll(state, 3); err != nil {
+		return err
+	}
+	var castedAsAny any = andOperator{ord: ord}
+	stackPush(state.opStack, castedAsAny)
+	stackPush(state.precedenceStack, 3)
+	return nil
+}
+
+func endsOperand(fragment queryFragment) bool {
+	return fragment.txt == ")" || isTermFragment(fragment) || isPhraseFragment(fragment) ||
+		isSubstringFragment(fragment) || isWildcardPattern(fragment.txt) || isBoostFragment(fragment)
+}
+
+func boostOperand(queryStack *[]core.Query, fragments []queryFragment, index int) error {
+	if index == 0 || isBoostFragment(fragments[index-1]) || !endsOperand(fragments[index-1]) || len(*queryStack) == 0 {
+		return fmt.Errorf("invalid query: boost %s must follow a term, a phrase or a group", fragments[index].txt)
+	}
+	boost, err := strconv.ParseFloat(fragments[index].txt[1:], 64)
+	if err != nil || boost <= 0 {
+		return fmt.Errorf("invalid query: invalid boost %s", fragments[index].txt)
+	}
+	stackPush(queryStack, core.Query(NewBoostedQuery(stackPop(queryStack), boost)))
+	return nil
+}
+
+func newOperandQuery(fragment queryFragment) (core.Query, error) {
+	if isSubstringFragment(fragment) {
+		substring := fragment.txt[1 : len(fragment.txt)-1]
+		if utf8.RuneCountInString(substring) < SubstringNGramSize || strings.ContainsFunc(substring, unicode.IsSpace) {
+			return nil, fmt.Errorf("invalid query: substrings must be single words of at least %d characters", SubstringNGramSize)
+		}
+		return NewSubstringQuery(substring), nil
+	}
+	if isPhraseFragment(fragment) {
+		words := phraseWords(fragment)
+		if len(words) == 0 {
+			return nil, fmt.Errorf("invalid query: empty phrase")
+		}
+		return NewPhraseQuery(words), nil
+	}
+	if isWildcardPattern(fragment.txt) {
+		return NewWildcardQuery(fragment.txt, DefaultMaxTermExpansions), nil
+	}
+	if fragment.opt > 0 {
+		return NewFuzzyQuery(fragment.txt, fragment.opt, DefaultMaxTermExpansions), nil
+	}
+	return &ExactQuery{term: fragment.txt}, nil
 }
 
 func ParseQuery(queryFragments []queryFragment) (core.Query, error) {
@@ -79,46 +232,63 @@ func ParseQuery(queryFragments []queryFragment) (core.Query, error) {
 	}
 
 	for index, fragment := range queryFragments {
+		var err error
 		switch fragment.txt {
 		case "(":
 			var castedAsAny any = openParenthesis{openingPosition: index}
 			stackPush(&opStack, castedAsAny)
 			stackPush(&precedenceStack, 0)
 		case "OR":
-			evaluateAll(parsingState, 1)
-			var castedAsAny any = ComplexQuery{ord: fragment.ord, policy: OrQueryPolicy}
-			stackPush(&opStack, castedAsAny)
+			err = evaluateAll(parsingState, 1)
+			stackPush(&opStack, newComplexOperator(fragment, OrQueryPolicy))
 			stackPush(&precedenceStack, 1)
 		case "XOR":
-			evaluateAll(parsingState, 2)
-			var castedAsAny any = ComplexQuery{ord: fragment.ord, policy: XorQueryPolicy}
-			stackPush(&opStack, castedAsAny)
+			err = evaluateAll(parsingState, 2)
+			stackPush(&opStack, newComplexOperator(fragment, XorQueryPolicy))
 			stackPush(&precedenceStack, 2)
 		case "AND":
-			evaluateAll(parsingState, 3)
-			var castedAsAny any = ComplexQuery{ord: fragment.ord, policy: AndQueryPolicy}
-			stackPush(&opStack, castedAsAny)
-			stackPush(&precedenceStack, 3)
+			err = pushAnd(parsingState, fragment.ord)
 		case "NEAR":
-			evaluateAll(parsingState, 4)
-			var castedAsAny any = ComplexQuery{ord: fragment.ord, policy: NearQueryPolicy(fragment.opt)}
-			stackPush(&opStack, castedAsAny)
+			err = evaluateAll(parsingState, 4)
+			stackPush(&opStack, newComplexOperator(fragment, NearQueryPolicy(fragment.opt)))
 			stackPush(&precedenceStack, 4)
+		case "NOT":
+			if index > 0 && endsOperand(queryFragments[index-1]) {
+				err = pushAnd(parsingState, false)
+			}
+			var castedAsAny any = NotQuery{}
+			stackPush(&opStack, castedAsAny)
+			stackPush(&precedenceStack, 5)
 		case ")":
-			for len(opStack) > 0 {
-				for precedence := stackPop(&precedenceStack); precedence != 0; {
-					evaluateOne(&queryStack, &opStack)
-					precedence = stackPop(&precedenceStack)
-				}
-				stackPop(&opStack)
+			for len(precedenceStack) > 0 && stackPop(&precedenceStack) != 0 && err == nil {
+				err = evaluateOne(&queryStack, &opStack)
 			}
+			stackPop(&opStack)
 		default:
-			queryStack = append(queryStack, &ExactQuery{term: fragment.txt})
+			if isBoostFragment(fragment) {
+				err = boostOperand(&queryStack, queryFragments, index)
+				break
+			}
+			if isFieldFragment(fragment) {
+				var castedAsAny any = FieldQuery{field: fieldName(fragment)}
+				stackPush(&opStack, castedAsAny)
+				stackPush(&precedenceStack, 6)
+				break
+			}
+			var operand core.Query
+			if operand, err = newOperandQuery(fragment); err == nil {
+				queryStack = append(queryStack, operand)
+			}
+		}
+		if err != nil {
+			return nil, err
 		}
 	}
 
 	for len(opStack) > 0 {
-		evaluateOne(&queryStack, &opStack)
+		if err := evaluateOne(&queryStack, &opStack); err != nil {
+			return nil, err
+		}
 	}
 
 	if len(queryStack) != 1 {
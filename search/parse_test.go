@@ -197,3 +197,199 @@ func TestParseUsefulParenOrThenAndQuery(t *testing.T) {
 		t.Errorf("Expected complex query with complex query on the right, got something else")
 	}
 }
+
+func TestParseParenDoesNotCloseOuterOperators(t *testing.T) {
+
+	// a OR [(b) AND c]
+	fragments := []queryFragment{
+		{"a", false, 0},
+		{"OR", false, 0},
+		{"(", false, 0},
+		{"b", false, 0},
+		{")", false, 0},
+		{"AND", false, 0},
+		{"c", false, 0},
+	}
+
+	query, err := ParseQuery(fragments)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+
+	if term := query.(*ComplexQuery).lx.(*ExactQuery).term; term != "a" {
+		t.Errorf("Expected complex query with exact match 'a' on the left, got something else: %v", term)
+	}
+
+	if term := query.(*ComplexQuery).rx.(*ComplexQuery).rx.(*ExactQuery).term; term != "c" {
+		t.Errorf("Expected complex query with exact match 'c' on the far right, got something else: %v", term)
+	}
+}
+
+func TestParseUnaryNotQuery(t *testing.T) {
+
+	// NOT a
+	fragments := []queryFragment{
+		{"NOT", false, 0},
+		{"a", false, 0},
+	}
+
+	query, err := ParseQuery(fragments)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+
+	if _, isAllDocuments := query.(*NotQuery).lx.(*AllDocumentsQuery); !isAllDocuments {
+		t.Errorf("Expected unary negation over every document, got something else")
+	}
+
+	if term := query.(*NotQuery).rx.(*ExactQuery).term; term != "a" {
+		t.Errorf("Expected negation of exact match 'a', got something else: %v", term)
+	}
+}
+
+func TestParseAndNotQuery(t *testing.T) {
+
+	// a AND [NOT b]
+	fragments := []queryFragment{
+		{"a", false, 0},
+		{"AND", false, 0},
+		{"NOT", false, 0},
+		{"b", false, 0},
+	}
+
+	query, err := ParseQuery(fragments)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+
+	if term := query.(*NotQuery).lx.(*ExactQuery).term; term != "a" {
+		t.Errorf("Expected exact match 'a' as positive side, got something else: %v", term)
+	}
+
+	if term := query.(*NotQuery).rx.(*ExactQuery).term; term != "b" {
+		t.Errorf("Expected exact match 'b' as negated side, got something else: %v", term)
+	}
+}
+
+func TestParseImplicitAndNotQuery(t *testing.T) {
+
+	// [b AND [NOT a]] OR c
+	fragments := []queryFragment{
+		{"b", false, 0},
+		{"NOT", false, 0},
+		{"a", false, 0},
+		{"OR", false, 0},
+		{"c", false, 0},
+	}
+
+	query, err := ParseQuery(fragments)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+
+	negation := query.(*ComplexQuery).lx.(*NotQuery)
+	if term := negation.lx.(*ExactQuery).term; term != "b" {
+		t.Errorf("Expected exact match 'b' as positive side, got something else: %v", term)
+	}
+
+	if term := query.(*ComplexQuery).rx.(*ExactQuery).term; term != "c" {
+		t.Errorf("Expected exact match 'c' on the right, got something else: %v", term)
+	}
+}
+
+func TestParsePhraseQuery(t *testing.T) {
+
+	// "a b" AND c
+	fragments := []queryFragment{
+		{`"a b"`, false, 0},
+		{"AND", false, 0},
+		{"c", false, 0},
+	}
+
+	query, err := ParseQuery(fragments)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+
+	phrase := query.(*ComplexQuery).lx.(*PhraseQuery)
+	if len(phrase.terms) != 2 || phrase.terms[0] != "a" || phrase.terms[1] != "b" {
+		t.Errorf("Expected phrase query over 'a b', got something else: %v", phrase.terms)
+	}
+
+	if _, err := ParseQuery([]queryFragment{{`" "`, false, 0}}); err == nil {
+		t.Errorf("Expected an error for an empty phrase")
+	}
+}
+
+func TestParseBoostedQuery(t *testing.T) {
+
+	// title:a^2 OR (b AND c)^3
+	fragments := []queryFragment{
+		{"title:", false, 0},
+		{"a", false, 0},
+		{"^2", false, 0},
+		{"OR", false, 0},
+		{"(", false, 0},
+		{"b", false, 0},
+		{"AND", false, 0},
+		{"c", false, 0},
+		{")", false, 0},
+		{"^3", false, 0},
+	}
+
+	query, err := ParseQuery(fragments)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+
+	field := query.(*ComplexQuery).lx.(*FieldQuery)
+	if boosted := field.query.(*BoostedQuery); boosted.boost != 2 || boosted.query.(*ExactQuery).term != "a" {
+		t.Errorf("Expected the field to wrap 'a' boosted by 2, got %v", boosted)
+	}
+	group := query.(*ComplexQuery).rx.(*BoostedQuery)
+	if _, isComplex := group.query.(*ComplexQuery); !isComplex || group.boost != 3 {
+		t.Errorf("Expected the group to be boosted by 3, got %v", group)
+	}
+
+	for _, invalid := range [][]queryFragment{
+		{{"^2", false, 0}, {"a", false, 0}},
+		{{"a", false, 0}, {"OR", false, 0}, {"^2", false, 0}},
+		{{"a", false, 0}, {"^2", false, 0}, {"^3", false, 0}},
+	} {
+		if _, err := ParseQuery(invalid); err == nil {
+			t.Errorf("Expected an error for a boost not following an operand: %v", invalid)
+		}
+	}
+}
+
+func TestParseMissingOperands(t *testing.T) {
+	for _, invalid := range []string{"NOT", "a OR NOT", "a AND", "AND", "AND a", "a NEAR:3", "a XOR (NOT)"} {
+		fragments, err := SplitQuery(invalid)
+		if err != nil {
+			t.Fatalf("Failed to split query %q: %v", invalid, err)
+		}
+		if _, err := ParseQuery(fragments); err == nil {
+			t.Errorf("Expected an error for a missing operand in %q", invalid)
+		}
+	}
+}
+
+func TestParseFieldedSubstrings(t *testing.T) {
+	for _, invalid := range []string{"title:'city'", "title:(guitar OR 'city')", "title:NOT 'city'"} {
+		fragments, err := SplitQuery(invalid)
+		if err != nil {
+			t.Fatalf("Failed to split query %q: %v", invalid, err)
+		}
+		if _, err := ParseQuery(fragments); err == nil {
+			t.Errorf("Expected an error for a substring restricted to a field in %q", invalid)
+		}
+	}
+
+	fragments, err := SplitQuery("title:guitar AND 'xk-4521'")
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+	if _, err := ParseQuery(fragments); err != nil {
+		t.Errorf("Expected a substring outside of a field to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+A "PhraseQuery" query is a type of search query that looks for a sequence of terms
+appearing at consecutive positions in a document (e.g. "new york city"). It holds
+one "ExactQuery" per term, and it keeps them aligned: the i-th term is always moved
+to the first occurrence at or after the position of the first term plus i. Whenever
+some term overshoots, the first term is moved forward accordingly, and the alignment
+starts over. Every configuration in which all the terms are aligned is a match.
+Unlike "NEAR:ORD:1", which only checks pairs of terms, this guarantees that the
+whole phrase appears exactly as written. "PhraseQuery" implements the Query
+interface, which defines the "Run", "Advance", and "Close" methods. Please refer to
+the documentation of the "Query" interface for more details about its methods and
+their intended usage.
+==================================================================================*/
+
+package search
+
+import (
+	"quinto/core"
+	"quinto/data"
+)
+
+type PhraseQuery struct {
+	terms   []string
+	queries []ExactQuery
+	aligned bool
+}
+
+func NewPhraseQuery(terms []string) *PhraseQuery {
+	return &PhraseQuery{terms: terms}
+}
+
+func comesBefore(
+	lxDocId core.DocumentId,
+	lxPos core.TermPosition,
+	rxDocId core.DocumentId,
+	rxPos core.TermPosition,
+) bool {
+	return lxDocId < rxDocId || (lxDocId == rxDocId && lxPos < rxPos)
+}
+
+func (q *PhraseQuery) Init(index core.ReverseIndex) {
+	q.queries = make([]ExactQuery, len(q.terms))
+	for i, term := range q.terms {
+		q.queries[i].term = term
+		q.queries[i].Init(index)
+	}
+	q.align()
+}
+
+func advanceTo(query *ExactQuery, docId core.DocumentId, position core.TermPosition) {
+	for !query.Ended() {
+		currentDocId, currentPosition := query.Coordinates()
+		if !comesBefore(currentDocId, currentPosition, docId, position) {
+			return
+		}
+		query.Advance()
+	}
+}
+
+func (q *PhraseQuery) align() {
+	q.aligned = false
+	first := &q.queries[0]
+	for !first.Ended() {
+		docId, position := first.Coordinates()
+		overshoot := false
+		for i := 1; i < len(q.queries) && !overshoot; i++ {
+			expected := position + core.TermPosition(i)
+			advanceTo(&q.queries[i], docId, expected)
+			if q.queries[i].Ended() {
+				return
+			}
+			actualDocId, actualPosition := q.queries[i].Coordinates()
+			if actualDocId != docId || actualPosition != expected {
+				overshoot = true
+				if actualPosition >= core.TermPosition(i) {
+					actualPosition -= core.TermPosition(i)
+				} else {
+					actualPosition = 0
+				}
+				advanceTo(first, actualDocId, actualPosition)
+			}
+		}
+		if !overshoot {
+			q.aligned = true
+			return
+		}
+	}
+}
+
+func (q *PhraseQuery) Run() core.Match {
+	if !q.aligned {
+		return core.Match{Success: false}
+	}
+	involvedTokens := data.NewSet[core.Token]()
+	for i := range q.queries {
+		match := q.queries[i].Run()
+		involvedTokens.InsertAll(&match.InvolvedTokens)
+	}
+	docId, position := q.queries[0].Coordinates()
+	return core.Match{
+		Success:        true,
+		DocId:          docId,
+		StartPosition:  position,
+		EndPosition:    position + core.TermPosition(len(q.queries)-1),
+		InvolvedTokens: involvedTokens,
+	}
+}
+
+func (q *PhraseQuery) Advance() {
+	if q.queries[0].Ended() {
+		return
+	}
+	q.queries[0].Advance()
+	q.align()
+}
+
+func (q *PhraseQuery) Ended() bool {
+	return !q.aligned
+}
+
+func (q *PhraseQuery) Close() {
+	for i := range q.queries {
+		q.queries[i].Close()
+	}
+}
+
+func (q *PhraseQuery) Coordinates() (core.DocumentId, core.TermPosition) {
+	return q.queries[0].Coordinates()
+}
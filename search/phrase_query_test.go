@@ -0,0 +1,59 @@
+package search
+
+import (
+	"quinto/core"
+	"quinto/data"
+	"testing"
+)
+
+func collectPhraseMatches(index core.ReverseIndex, terms ...string) []core.Match {
+	query := NewPhraseQuery(terms)
+	query.Init(index)
+	defer query.Close()
+	matches := []core.Match{}
+	for !query.Ended() {
+		if match := query.Run(); match.Success {
+			matches = append(matches, match)
+		}
+		query.Advance()
+	}
+	return matches
+}
+
+func TestPhraseQueryMatchesConsecutivePositionsOnly(t *testing.T) {
+	index := NewNaiveReverseIndex()
+	index.StoreNewDocument(data.NewSliceIterator(createDummyDocument([]string{"york", "new", "city"})))
+	index.StoreNewDocument(data.NewSliceIterator(createDummyDocument([]string{"new", "york", "new", "york", "city"})))
+	index.StoreNewDocument(data.NewSliceIterator(createDummyDocument([]string{"new", "york", "big", "city"})))
+
+	matches := collectPhraseMatches(index, "new", "york", "city")
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 match, got %d", len(matches))
+	}
+
+	if matches[0].DocId != 2 || matches[0].StartPosition != 2 || matches[0].EndPosition != 4 {
+		t.Errorf("Expected a match in document 2 spanning positions 2-4, got %+v", matches[0])
+	}
+
+	if size := matches[0].InvolvedTokens.Size(); size != 3 {
+		t.Errorf("Expected 3 involved tokens, got %d", size)
+	}
+}
+
+func TestPhraseQueryWithRepeatedTerm(t *testing.T) {
+	index := NewNaiveReverseIndex()
+	index.StoreNewDocument(data.NewSliceIterator(createDummyDocument([]string{"bye", "bye", "bye"})))
+
+	if matches := collectPhraseMatches(index, "bye", "bye"); len(matches) != 2 {
+		t.Errorf("Expected 2 overlapping matches, got %d", len(matches))
+	}
+}
+
+func TestPhraseQueryWithMissingTerm(t *testing.T) {
+	index := NewNaiveReverseIndex()
+	index.StoreNewDocument(data.NewSliceIterator(createDummyDocument([]string{"new", "york"})))
+
+	if matches := collectPhraseMatches(index, "new", "jersey"); len(matches) != 0 {
+		t.Errorf("Expected no matches, got %d", len(matches))
+	}
+}
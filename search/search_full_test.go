@@ -1,12 +1,32 @@
 package search
 
 import (
+	"context"
 	"quinto/core"
 	"quinto/data"
 	"testing"
+	"time"
 )
 
-func runTestCollectMatchesHelper(t *testing.T, queryString string) map[core.DocumentId]core.Match {
+const maxTestQuerySteps = 300
+
+// Counts the steps of the wrapped query, cancelling the execution as soon as they
+// exceed the given limit (a query that never ends would otherwise hang the tests).
+type stepLimitedQuery struct {
+	core.Query
+	steps  int
+	limit  int
+	cancel context.CancelFunc
+}
+
+func (q *stepLimitedQuery) Run() core.Match {
+	if q.steps++; q.steps > q.limit {
+		q.cancel()
+	}
+	return q.Query.Run()
+}
+
+func runTestCollectMatchesHelper(t *testing.T, queryString string) []core.SearchResult {
 
 	index := NewNaiveReverseIndex()
 	index.StoreNewDocument(data.NewSliceIterator(helloWorldDocument))
@@ -20,29 +40,16 @@ func runTestCollectMatchesHelper(t *testing.T, queryString string) map[core.Docu
 		t.Fatalf("Failed to parse query: %v %v", err1, err2)
 	}
 
-	defer query.Close()
-	results := map[core.DocumentId]core.Match{}
-
-	query.Init(index)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
 
-	counter := 0
-	for !query.Ended() {
-		if counter > 300 {
-			t.Fatalf("Infinite loop detected in query execution")
-		}
-		counter++
-		queryResult := query.Run()
-		if queryResult.Success {
-			if match, exists := results[queryResult.DocId]; exists {
-				match.InvolvedTokens.InsertAll(&queryResult.InvolvedTokens)
-			} else {
-				results[queryResult.DocId] = queryResult
-			}
-		}
-		query.Advance()
+	limitedQuery := &stepLimitedQuery{Query: query, limit: maxTestQuerySteps, cancel: cancel}
+	results := NewBoundedResultSet(100)
+	if err := ExecuteContext(ctx, limitedQuery, index, results, ExecutionConfig{}); err != nil {
+		t.Fatalf("Infinite loop detected in query execution (%d steps): %v", limitedQuery.steps, err)
 	}
 
-	return results
+	return results.SortedSlice()
 }
 
 func TestFirstComplexQueryOverMultipleDocuments(t *testing.T) {
@@ -72,3 +79,134 @@ func TestFourthComplexQueryOverMultipleDocuments(t *testing.T) {
 		t.Errorf("Expected 2 match, got %d", len(matches))
 	}
 }
+
+func TestDisjunctionOverDisjointDocuments(t *testing.T) {
+	matches := runTestCollectMatchesHelper(t, "hello OR drill")
+	if len(matches) != 2 {
+		t.Errorf("Expected 2 match, got %d", len(matches))
+	}
+}
+
+func TestDisjunctionOverOverlappingDocuments(t *testing.T) {
+	matches := runTestCollectMatchesHelper(t, "instrument OR music")
+	if len(matches) != 3 {
+		t.Errorf("Expected 3 match, got %d", len(matches))
+	}
+}
+
+func TestAndNotQueryOverMultipleDocuments(t *testing.T) {
+	matches := runTestCollectMatchesHelper(t, "instrument AND NOT guitar")
+	if len(matches) != 1 || matches[0].DocId != 4 {
+		t.Errorf("Expected only the tools document, got %v", matches)
+	}
+}
+
+func TestImplicitAndNotQueryOverMultipleDocuments(t *testing.T) {
+	matches := runTestCollectMatchesHelper(t, "music NOT (chess OR hello)")
+	if len(matches) != 1 || matches[0].DocId != 2 {
+		t.Errorf("Expected only the guitar document, got %v", matches)
+	}
+}
+
+func TestUnaryNotQueryOverMultipleDocuments(t *testing.T) {
+	matches := runTestCollectMatchesHelper(t, "NOT instrument")
+	if len(matches) != 2 {
+		t.Errorf("Expected 2 match, got %d", len(matches))
+	}
+	for _, match := range matches {
+		if match.DocId == 2 || match.DocId == 4 {
+			t.Errorf("Expected document %d to be excluded", match.DocId)
+		}
+	}
+}
+
+func TestDoubleNegationQueryOverMultipleDocuments(t *testing.T) {
+	matches := runTestCollectMatchesHelper(t, "NOT NOT music")
+	if len(matches) != 2 {
+		t.Errorf("Expected 2 match, got %d", len(matches))
+	}
+}
+
+func TestNegatedConjunctionOverMultipleDocuments(t *testing.T) {
+	matches := runTestCollectMatchesHelper(t, "instrument AND NOT (music AND string)")
+	if len(matches) != 1 || matches[0].DocId != 4 {
+		t.Errorf("Expected only the tools document, got %v", matches)
+	}
+}
+
+func TestPhraseQueryOverMultipleDocuments(t *testing.T) {
+	matches := runTestCollectMatchesHelper(t, `"important music instrument" OR "hello world"`)
+	if len(matches) != 2 {
+		t.Errorf("Expected 2 match, got %d", len(matches))
+	}
+}
+
+func TestPhraseQueryWithWrongOrderOverMultipleDocuments(t *testing.T) {
+	matches := runTestCollectMatchesHelper(t, `"world hello" OR "instrument music"`)
+	if len(matches) != 0 {
+		t.Errorf("Expected 0 match, got %d", len(matches))
+	}
+}
+
+func TestWildcardQueryOverMultipleDocuments(t *testing.T) {
+	matches := runTestCollectMatchesHelper(t, "instr* AND NOT gu?tar")
+	if len(matches) != 1 || matches[0].DocId != 4 {
+		t.Errorf("Expected only the tools document, got %v", matches)
+	}
+}
+
+func TestFuzzyQueryOverMultipleDocuments(t *testing.T) {
+	matches := runTestCollectMatchesHelper(t, "scrwdriver~1 OR hamer~")
+	if len(matches) != 1 || matches[0].DocId != 4 {
+		t.Errorf("Expected only the tools document, got %v", matches)
+	}
+}
+
+func TestQueryOverDeletedDocuments(t *testing.T) {
+	index := createExecutionTestIndex()
+	if err := index.DeleteDocument(2); err != nil {
+		t.Fatalf("Failed to delete document: %v", err)
+	}
+
+	results := NewBoundedResultSet(10)
+	if err := Execute(parseTestQuery(t, "instrument OR NOT hello"), index, results); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for result := range results.Iterate() {
+		if result.DocId == 2 {
+			t.Errorf("Expected deleted document 2 not to be found")
+		}
+	}
+
+	if size := len(results.SortedSlice()); size != 2 {
+		t.Errorf("Expected 2 results, got %d", size)
+	}
+}
+
+func TestAttachStoredDocumentsToResults(t *testing.T) {
+	index := createExecutionTestIndex()
+	index.StoreDocumentFields(2, core.StoredDocument{
+		Text:     "guitar string instrument",
+		Metadata: map[string]string{"title": "Guitars"},
+	})
+
+	results := NewBoundedResultSet(10)
+	if err := Execute(parseTestQuery(t, "instrument"), index, results); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	sorted := results.SortedSlice()
+	if err := AttachStoredDocuments(sorted, index); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, result := range sorted {
+		if result.DocId == 2 && (result.Document == nil || result.Document.Metadata["title"] != "Guitars") {
+			t.Errorf("Expected the stored fields of document 2, got %v", result.Document)
+		}
+		if result.DocId != 2 && result.Document != nil {
+			t.Errorf("Expected document %d not to have stored fields", result.DocId)
+		}
+	}
+}
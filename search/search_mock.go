@@ -1,8 +1,13 @@
 package search
 
 import (
+	"fmt"
 	"iter"
+	"maps"
 	"quinto/core"
+	"quinto/data"
+	"slices"
+	"strings"
 	"sync/atomic"
 )
 
@@ -48,14 +53,26 @@ func createDummyDocument(tokens []string) []core.Token {
 }
 
 type NaiveReverseIndex struct {
-	terms     map[string][]core.TermTracker
-	IdCounter atomic.Uint64
+	terms           map[string][]core.TermTracker
+	nGrams          map[string][]core.TermTracker
+	fieldTerms      map[string]map[string][]core.TermTracker
+	fieldBoosts     map[string]float64
+	documentLengths map[core.DocumentId]uint64
+	externalIds     map[string]core.DocumentId
+	storedDocuments map[core.DocumentId]core.StoredDocument
+	IdCounter       atomic.Uint64
 }
 
 func NewNaiveReverseIndex() *NaiveReverseIndex {
 	return &NaiveReverseIndex{
-		terms:     make(map[string][]core.TermTracker),
-		IdCounter: atomic.Uint64{},
+		terms:           make(map[string][]core.TermTracker),
+		nGrams:          make(map[string][]core.TermTracker),
+		fieldTerms:      make(map[string]map[string][]core.TermTracker),
+		fieldBoosts:     make(map[string]float64),
+		documentLengths: make(map[core.DocumentId]uint64),
+		externalIds:     make(map[string]core.DocumentId),
+		storedDocuments: make(map[core.DocumentId]core.StoredDocument),
+		IdCounter:       atomic.Uint64{},
 	}
 }
 
@@ -73,11 +90,179 @@ func (q *NaiveReverseIndex) IterateOverTerms(term string) iter.Seq[core.TermTrac
 	}
 }
 
+func (q *NaiveReverseIndex) IterateOverDocuments() iter.Seq[core.DocumentId] {
+	return func(yield func(core.DocumentId) bool) {
+		for id := core.DocumentId(1); id <= core.DocumentId(q.IdCounter.Load()); id++ {
+			if _, exists := q.documentLengths[id]; exists && !yield(id) {
+				return
+			}
+		}
+	}
+}
+
+func (q *NaiveReverseIndex) IterateOverDictionary(prefix string) iter.Seq[string] {
+	terms := []string{}
+	for term := range q.terms {
+		if strings.HasPrefix(term, prefix) {
+			terms = append(terms, term)
+		}
+	}
+	slices.Sort(terms)
+	return data.NewSliceIterator(terms)
+}
+
 func (q *NaiveReverseIndex) StoreNewDocument(toks iter.Seq[core.Token]) (core.DocumentId, error) {
 	id := core.DocumentId(q.IdCounter.Add(1))
+	q.documentLengths[id] = 0
 	for tok := range toks {
 		newTracker := core.TermTracker{DocId: id, Position: tok.Position}
 		q.terms[tok.StemmedText] = append(q.terms[tok.StemmedText], newTracker)
+		q.documentLengths[id]++
 	}
 	return id, nil
 }
+
+func (q *NaiveReverseIndex) UpsertDocument(externalId string, toks iter.Seq[core.Token]) (core.DocumentId, error) {
+	if oldId, exists := q.externalIds[externalId]; exists {
+		q.DeleteDocument(oldId)
+	}
+	id, err := q.StoreNewDocument(toks)
+	q.externalIds[externalId] = id
+	return id, err
+}
+
+func (q *NaiveReverseIndex) DeleteDocument(docId core.DocumentId) error {
+	if _, exists := q.documentLengths[docId]; !exists {
+		return fmt.Errorf("document %d does not exist", docId)
+	}
+	delete(q.documentLengths, docId)
+	for _, trackers := range append([]map[string][]core.TermTracker{q.terms, q.nGrams}, slices.Collect(maps.Values(q.fieldTerms))...) {
+		for term, termTrackers := range trackers {
+			trackers[term] = slices.DeleteFunc(termTrackers, func(tracker core.TermTracker) bool {
+				return tracker.DocId == docId
+			})
+		}
+	}
+	return nil
+}
+
+func (q *NaiveReverseIndex) IterateOverFieldTerms(field string, term string) iter.Seq[core.TermTracker] {
+	return data.NewSliceIterator(q.fieldTerms[field][term])
+}
+
+func (q *NaiveReverseIndex) IterateOverFieldDictionary(field string, prefix string) iter.Seq[string] {
+	terms := []string{}
+	for term := range q.fieldTerms[field] {
+		if strings.HasPrefix(term, prefix) {
+			terms = append(terms, term)
+		}
+	}
+	slices.Sort(terms)
+	return data.NewSliceIterator(terms)
+}
+
+func (q *NaiveReverseIndex) StoreNewFieldedDocument(fields []core.DocumentField) (core.DocumentId, error) {
+	fieldTokens := map[string][]core.Token{}
+	tokens := []core.Token{}
+	for field, token := range core.IterateFieldTokens(fields) {
+		fieldTokens[field] = append(fieldTokens[field], token)
+		tokens = append(tokens, token)
+	}
+	id, err := q.StoreNewDocument(data.NewSliceIterator(tokens))
+	for _, field := range fields {
+		if field.Boost > 0 {
+			q.fieldBoosts[field.Name] = field.Boost
+		}
+	}
+	for field, toks := range fieldTokens {
+		if q.fieldTerms[field] == nil {
+			q.fieldTerms[field] = make(map[string][]core.TermTracker)
+		}
+		for _, tok := range toks {
+			newTracker := core.TermTracker{DocId: id, Position: tok.Position}
+			q.fieldTerms[field][tok.StemmedText] = append(q.fieldTerms[field][tok.StemmedText], newTracker)
+		}
+	}
+	return id, err
+}
+
+func (q *NaiveReverseIndex) FieldBoost(field string) float64 {
+	if boost, exists := q.fieldBoosts[field]; exists {
+		return boost
+	}
+	return 1
+}
+
+func (q *NaiveReverseIndex) UpsertFieldedDocument(externalId string, fields []core.DocumentField) (core.DocumentId, error) {
+	if oldId, exists := q.externalIds[externalId]; exists {
+		q.DeleteDocument(oldId)
+	}
+	id, err := q.StoreNewFieldedDocument(fields)
+	q.externalIds[externalId] = id
+	return id, err
+}
+
+func (q *NaiveReverseIndex) IterateOverNGrams(nGram string) iter.Seq[core.TermTracker] {
+	return data.NewSliceIterator(q.nGrams[nGram])
+}
+
+func (q *NaiveReverseIndex) StoreDocumentNGrams(docId core.DocumentId, nGrams iter.Seq[core.Token]) error {
+	for nGram := range nGrams {
+		tracker := core.TermTracker{DocId: docId, Position: nGram.Position}
+		if !slices.Contains(q.nGrams[nGram.StemmedText], tracker) {
+			q.nGrams[nGram.StemmedText] = append(q.nGrams[nGram.StemmedText], tracker)
+		}
+	}
+	return nil
+}
+
+func (q *NaiveReverseIndex) StoreDocumentFields(docId core.DocumentId, document core.StoredDocument) error {
+	q.storedDocuments[docId] = document
+	return nil
+}
+
+func (q *NaiveReverseIndex) StoredLanguages() []string {
+	languages := []string{}
+	for _, document := range q.storedDocuments {
+		if document.Language != "" && !slices.Contains(languages, document.Language) {
+			languages = append(languages, document.Language)
+		}
+	}
+	slices.Sort(languages)
+	return languages
+}
+
+func (q *NaiveReverseIndex) LoadDocumentFields(docId core.DocumentId) (core.StoredDocument, bool, error) {
+	if _, exists := q.documentLengths[docId]; !exists {
+		return core.StoredDocument{}, false, nil
+	}
+	document, exists := q.storedDocuments[docId]
+	return document, exists, nil
+}
+
+func (q *NaiveReverseIndex) DocumentsCount() uint64 {
+	return uint64(len(q.documentLengths))
+}
+
+func (q *NaiveReverseIndex) DocumentLength(docId core.DocumentId) uint64 {
+	return q.documentLengths[docId]
+}
+
+func (q *NaiveReverseIndex) AverageDocumentLength() float64 {
+	if len(q.documentLengths) == 0 {
+		return 0
+	}
+	totalLength := uint64(0)
+	for _, length := range q.documentLengths {
+		totalLength += length
+	}
+	return float64(totalLength) / float64(len(q.documentLengths))
+}
+
+func (q *NaiveReverseIndex) DocumentFrequency(term string) uint64 {
+	documents := data.NewSet[core.DocumentId]()
+	for _, tracker := range q.terms[term] {
+		documents.InsertOne(tracker.DocId)
+	}
+	return uint64(documents.Size())
+}
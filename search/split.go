@@ -6,12 +6,20 @@
 
 ============================== BRIEF FILE DESCRIPTION ===============================
 
-This file contains the implementation of the SplitQuery function, which is responsible
-for splitting a query string into its constituent fragments. The function uses regular
-expressions to identify different types of fragments, including simple terms, complex
-queries, and parentheses. The fragments are stored in a slice of queryFragment structs,
-which contain the text of the fragment, a boolean indicating whether, in case of
-complex queries, the order is important, and an integer for additional options.
+This file contains the implementation of the SplitQuery function, which is
+responsible for splitting a query string into its constituent fragments. The function
+uses regular expressions to identify different types of fragments, including simple
+terms (lower case words of any script or numbers, possibly with "*" and "?"
+wildcards, or followed by "~N" for fuzzy matching), complex queries, parentheses,
+quoted phrases (kept as a single fragment, quotes included), single-quoted substrings
+(e.g. 'xk-4521', kept as they are, case included) and field prefixes (e.g. "title:",
+colon included), which restrict the operand that follows them to a single field of
+the documents, and boosts (e.g. "^2.5", caret included), which multiply the score of
+the operand that precedes them. The fragments are stored in a slice of queryFragment
+structs, which contain the text of the fragment, a boolean indicating whether, in
+case of complex queries, the order is important, and an integer for additional
+options (the distance of "NEAR" queries, or the maximum edit distance of fuzzy
+terms).
 ==================================================================================*/
 
 package search
@@ -21,6 +29,9 @@ import (
 	"quinto/data"
 	"regexp"
 	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type queryFragment struct {
@@ -44,16 +55,76 @@ func extractParenthesis(query string, index *int, fragments *[]queryFragment) er
 	return errors.New("invalid parenthesis")
 }
 
+func extractPhraseQueryFragment(query string, index *int, fragments *[]queryFragment) error {
+	quote := query[*index]
+	closingQuoteOffset := strings.IndexByte(query[*index+1:], quote)
+	if closingQuoteOffset < 0 && quote == '\'' {
+		return errors.New("unterminated substring in query")
+	}
+	if closingQuoteOffset < 0 {
+		return errors.New("unterminated phrase in query")
+	}
+	phrase := query[*index : *index+closingQuoteOffset+2]
+	*fragments = append(*fragments, queryFragment{phrase, false, 0})
+	*index += len(phrase)
+	return nil
+}
+
+var fieldPrefixRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*:`)
+
+func extractFieldQueryFragment(query string, index *int, fragments *[]queryFragment) error {
+	prefix := fieldPrefixRegex.FindString(query[*index:])
+	*fragments = append(*fragments, queryFragment{prefix, false, 0})
+	*index += len(prefix)
+	return nil
+}
+
+var boostRegex = regexp.MustCompile(`^\^(\d+(?:\.\d+)?)`)
+
+func extractBoostFragment(query string, index *int, fragments *[]queryFragment) error {
+	matches := boostRegex.FindStringSubmatch(query[*index:])
+	if matches == nil {
+		return errors.New("invalid boost in query: boosts must be positive numbers, e.g. ^2.5")
+	}
+	if boost, err := strconv.ParseFloat(matches[1], 64); err != nil || boost <= 0 {
+		return errors.New("invalid boost in query: " + matches[0])
+	}
+	*fragments = append(*fragments, queryFragment{matches[0], false, 0})
+	*index += len(matches[0])
+	return nil
+}
+
+func extractFuzzyDistance(term string, tilde string, digits string) (int, error) {
+	if tilde == "" {
+		return 0, nil
+	}
+	if strings.ContainsAny(term, "*?") {
+		return 0, errors.New("fuzzy matching is not supported on wildcard patterns: " + term)
+	}
+	if digits == "" {
+		return MaxFuzzyDistance, nil
+	}
+	distance, err := strconv.Atoi(digits)
+	if err != nil || distance > MaxFuzzyDistance {
+		return 0, errors.New("invalid fuzzy distance in simple query fragment: " + term + tilde)
+	}
+	return distance, nil
+}
+
 func extractSimpleQueryFragment(query string, index *int, fragments *[]queryFragment) error {
-	fragmentRegex := regexp.MustCompile(`([a-z]+)`)
+	fragmentRegex := regexp.MustCompile(`^([\p{Ll}\p{Lo}\p{Nd}\p{M}*?]+)(~(\d*))?`)
 	matches := fragmentRegex.FindStringSubmatch(query[*index:])
 	if matches == nil {
 		return errors.New("impossible match of simple query fragment")
 	}
+	distance, err := extractFuzzyDistance(matches[1], matches[2], matches[3])
+	if err != nil {
+		return err
+	}
 	result := queryFragment{
 		txt: matches[1],
 		ord: false,
-		opt: 0,
+		opt: distance,
 	}
 	*fragments = append(*fragments, result)
 	*index += len(matches[0])
@@ -97,7 +168,11 @@ func SplitQuery(query string) ([]queryFragment, error) {
 			index++
 			continue
 		}
-		if char >= 'a' && char <= 'z' {
+		if fieldPrefixRegex.MatchString(query[index:]) {
+			err = extractFieldQueryFragment(query, &index, &fragments)
+			continue
+		}
+		if r, _ := utf8.DecodeRuneInString(query[index:]); unicode.In(r, unicode.Ll, unicode.Lo, unicode.Nd) || char == '*' || char == '?' {
 			err = extractSimpleQueryFragment(query, &index, &fragments)
 			continue
 		}
@@ -105,11 +180,20 @@ func SplitQuery(query string) ([]queryFragment, error) {
 			err = extractComplexQueryFragment(query, &index, &fragments)
 			continue
 		}
+		if char == '"' || char == '\'' {
+			err = extractPhraseQueryFragment(query, &index, &fragments)
+			continue
+		}
 		if char == '(' || char == ')' {
 			err = extractParenthesis(query, &index, &fragments)
 			continue
 		}
-		err = errors.New("invalid character in query: " + string(char))
+		if char == '^' {
+			err = extractBoostFragment(query, &index, &fragments)
+			continue
+		}
+		r, _ := utf8.DecodeRuneInString(query[index:])
+		err = errors.New("invalid character in query: " + string(r))
 	}
 	return fragments, err
 }
@@ -128,3 +128,150 @@ func TestBadSplittingErrorRecovery(t *testing.T) {
 		t.Fatalf("Expected an error due to bad query syntax, but got none")
 	}
 }
+
+func TestSplitPhraseQuery(t *testing.T) {
+	fragments, err := SplitQuery(`"New York  city" AND nyc`)
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+
+	if len(fragments) != 3 {
+		t.Fatalf("Expected 3 fragments, got %d", len(fragments))
+	}
+
+	if fragments[0].txt != `"New York  city"` {
+		t.Errorf("Expected the whole phrase as a single fragment, got '%s'", fragments[0].txt)
+	}
+
+	if _, err := SplitQuery(`"new york`); err == nil {
+		t.Errorf("Expected an error for an unterminated phrase")
+	}
+}
+
+func TestSplitWildcardQuery(t *testing.T) {
+	fragments, err := SplitQuery("comput* OR *ing OR te?t")
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+
+	expected := []string{"comput*", "OR", "*ing", "OR", "te?t"}
+	if len(fragments) != len(expected) {
+		t.Fatalf("Expected %d fragments, got %d", len(expected), len(fragments))
+	}
+
+	for i := range expected {
+		if fragments[i].txt != expected[i] {
+			t.Errorf("Expected fragment '%s', got '%s'", expected[i], fragments[i].txt)
+		}
+	}
+}
+
+func TestSplitFuzzyQuery(t *testing.T) {
+	fragments, err := SplitQuery("guitr~1 AND drums~")
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+
+	expected := []queryFragment{
+		{"guitr", false, 1},
+		{"AND", false, 0},
+		{"drums", false, MaxFuzzyDistance},
+	}
+	if len(fragments) != len(expected) {
+		t.Fatalf("Expected %d fragments, got %d", len(expected), len(fragments))
+	}
+
+	for i := range expected {
+		if fragments[i] != expected[i] {
+			t.Errorf("Expected fragment %v, got %v", expected[i], fragments[i])
+		}
+	}
+
+	for _, invalid := range []string{"guitar~9", "gu*tar~1"} {
+		if _, err := SplitQuery(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestSplitNonAsciiQuery(t *testing.T) {
+	fragments, err := SplitQuery("café AND naïv* OR привет~1 OR 日本")
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+
+	expected := []string{"café", "AND", "naïv*", "OR", "привет", "OR", "日本"}
+	if len(fragments) != len(expected) {
+		t.Fatalf("Expected %d fragments, got %d", len(expected), len(fragments))
+	}
+
+	for i := range expected {
+		if fragments[i].txt != expected[i] {
+			t.Errorf("Expected fragment '%s', got '%s'", expected[i], fragments[i].txt)
+		}
+	}
+}
+
+func TestSplitQueryWithDigits(t *testing.T) {
+	fragments, err := SplitQuery("2024 AND mp3~1 OR ١٢٣ OR covid19^2")
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+
+	expected := []string{"2024", "AND", "mp3", "OR", "١٢٣", "OR", "covid19", "^2"}
+	if len(fragments) != len(expected) {
+		t.Fatalf("Expected %d fragments, got %d", len(expected), len(fragments))
+	}
+
+	for i := range expected {
+		if fragments[i].txt != expected[i] {
+			t.Errorf("Expected fragment '%s', got '%s'", expected[i], fragments[i].txt)
+		}
+	}
+
+	if fragments[2].opt != 1 {
+		t.Errorf("Expected a fuzzy distance of 1 for mp3, got %d", fragments[2].opt)
+	}
+}
+
+func TestSplitSubstringQuery(t *testing.T) {
+	fragments, err := SplitQuery(`'XK-4521' OR "rock band"`)
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+
+	expected := []string{"'XK-4521'", "OR", `"rock band"`}
+	if len(fragments) != len(expected) {
+		t.Fatalf("Expected %d fragments, got %d", len(expected), len(fragments))
+	}
+
+	for i := range expected {
+		if fragments[i].txt != expected[i] {
+			t.Errorf("Expected fragment '%s', got '%s'", expected[i], fragments[i].txt)
+		}
+	}
+}
+
+func TestSplitBoostedQuery(t *testing.T) {
+	fragments, err := SplitQuery(`guitar^2.5 OR "rock band"^3 OR (drums AND bass)^0.5`)
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+
+	expected := []string{"guitar", "^2.5", "OR", `"rock band"`, "^3", "OR", "(", "drums", "AND", "bass", ")", "^0.5"}
+	if len(fragments) != len(expected) {
+		t.Fatalf("Expected %d fragments, got %d", len(expected), len(fragments))
+	}
+
+	for i := range expected {
+		if fragments[i].txt != expected[i] {
+			t.Errorf("Expected fragment '%s', got '%s'", expected[i], fragments[i].txt)
+		}
+	}
+
+	for _, invalid := range []string{"guitar^", "guitar^0", "guitar^-1", "guitar^x"} {
+		if _, err := SplitQuery(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
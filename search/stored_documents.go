@@ -0,0 +1,33 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+This file contains the AttachStoredDocuments function, which loads the stored fields
+(original text and metadata) of the documents referred by a bunch of "SearchResult",
+and attaches them to the results themselves. It is meant to be called on the final
+results of a query (e.g. the top-k ones), rather than during the execution of the
+query, since loading stored fields is way more expensive than scoring.
+==================================================================================*/
+
+package search
+
+import (
+	"quinto/core"
+)
+
+func AttachStoredDocuments(results []core.SearchResult, store core.DocumentStore) error {
+	for i := range results {
+		document, exists, err := store.LoadDocumentFields(results[i].DocId)
+		if err != nil {
+			return err
+		}
+		if exists {
+			results[i].Document = &document
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+A "SubstringQuery" query is a type of search query that looks for the words of a
+document containing a given substring (e.g. "4521" inside "XK-45210"), regardless
+of the terms the words have been turned into. It relies on the n-gram index of the
+reverse index (see "core.NGramIndex"): the substring is split into its (lower-case)
+n-grams, each one being iterated by an "ExactQuery" over its n-gram postings, and the
+queries are kept aligned on the same document and position, that is, on the words
+containing every n-gram of the substring. Since a word containing all the n-grams
+does not necessarily contain the substring (e.g. "abcab" contains the trigrams of
+"bcabc"), every candidate document is verified against its stored text, when
+available. Substrings must be at least "SubstringNGramSize" characters long, and
+they cannot span multiple words. "SubstringQuery" implements the Query interface,
+which defines the "Run", "Advance", and "Close" methods. Please refer to the
+documentation of the "Query" interface for more details about its methods and their
+intended usage.
+==================================================================================*/
+
+package search
+
+import (
+	"quinto/core"
+	"quinto/data"
+	"slices"
+	"strings"
+)
+
+const SubstringNGramSize = 3
+
+type SubstringQuery struct {
+	substring     string
+	queries       []ExactQuery
+	store         core.DocumentStore
+	aligned       bool
+	verifiedDocId core.DocumentId
+	verified      bool
+}
+
+func NewSubstringQuery(substring string) *SubstringQuery {
+	return &SubstringQuery{substring: strings.ToLower(substring)}
+}
+
+func substringNGrams(text string, size int) []string {
+	runes := []rune(strings.ToLower(text))
+	nGrams := []string{}
+	for start := 0; start+size <= len(runes); start++ {
+		if nGram := string(runes[start : start+size]); !slices.Contains(nGrams, nGram) {
+			nGrams = append(nGrams, nGram)
+		}
+	}
+	return nGrams
+}
+
+func (q *SubstringQuery) Init(index core.ReverseIndex) {
+	index = unwrapIndexView(index)
+	q.queries = nil
+	q.store, _ = index.(core.DocumentStore)
+	q.verifiedDocId, q.verified = 0, false
+	if nGramIndex, isNGramIndex := index.(core.NGramIndex); isNGramIndex {
+		for _, nGram := range substringNGrams(q.substring, SubstringNGramSize) {
+			q.queries = append(q.queries, NewExactQuery(nGramIndex.IterateOverNGrams(nGram)))
+		}
+	}
+	q.align()
+}
+
+func (q *SubstringQuery) isVerified(docId core.DocumentId) bool {
+	if q.verifiedDocId == docId {
+		return q.verified
+	}
+	q.verifiedDocId, q.verified = docId, true
+	if q.store != nil {
+		document, exists, err := q.store.LoadDocumentFields(docId)
+		if err == nil && exists {
+			q.verified = strings.Contains(strings.ToLower(document.Text), q.substring)
+		}
+	}
+	return q.verified
+}
+
+func (q *SubstringQuery) align() {
+	q.aligned = false
+	if len(q.queries) == 0 {
+		return
+	}
+	first := &q.queries[0]
+	for !first.Ended() {
+		docId, position := first.Coordinates()
+		if !q.isVerified(docId) {
+			advanceTo(first, docId+1, 0)
+			continue
+		}
+		overshoot := false
+		for i := 1; i < len(q.queries) && !overshoot; i++ {
+			advanceTo(&q.queries[i], docId, position)
+			if q.queries[i].Ended() {
+				return
+			}
+			actualDocId, actualPosition := q.queries[i].Coordinates()
+			if actualDocId != docId || actualPosition != position {
+				overshoot = true
+				advanceTo(first, actualDocId, actualPosition)
+			}
+		}
+		if !overshoot {
+			q.aligned = true
+			return
+		}
+	}
+}
+
+func (q *SubstringQuery) Run() core.Match {
+	if !q.aligned {
+		return core.Match{Success: false}
+	}
+	docId, position := q.queries[0].Coordinates()
+	involvedTokens := data.NewSet[core.Token]()
+	involvedTokens.InsertOne(core.Token{
+		StemmedText: q.substring,
+		Position:    position,
+	})
+	return core.Match{
+		Success:        true,
+		DocId:          docId,
+		StartPosition:  position,
+		EndPosition:    position,
+		InvolvedTokens: involvedTokens,
+	}
+}
+
+func (q *SubstringQuery) Advance() {
+	if !q.aligned {
+		return
+	}
+	q.queries[0].Advance()
+	q.align()
+}
+
+func (q *SubstringQuery) Ended() bool {
+	return !q.aligned
+}
+
+func (q *SubstringQuery) Close() {
+	for i := range q.queries {
+		q.queries[i].Close()
+	}
+}
+
+func (q *SubstringQuery) Coordinates() (core.DocumentId, core.TermPosition) {
+	if !q.aligned {
+		return 0, 0
+	}
+	return q.queries[0].Coordinates()
+}
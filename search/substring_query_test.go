@@ -0,0 +1,103 @@
+package search
+
+import (
+	"context"
+	"quinto/core"
+	"quinto/data"
+	"slices"
+	"testing"
+	"time"
+)
+
+func storeSubstringTestDocument(index *NaiveReverseIndex, text string, nGrams ...string) core.DocumentId {
+	tokens := []core.Token{}
+	for position, word := range nGrams {
+		for _, nGram := range substringNGrams(word, SubstringNGramSize) {
+			tokens = append(tokens, core.Token{StemmedText: nGram, Position: core.TermPosition(position)})
+		}
+	}
+	docId, _ := index.StoreNewDocument(data.NewSliceIterator(createDummyDocument(nGrams)))
+	index.StoreDocumentNGrams(docId, data.NewSliceIterator(tokens))
+	if text != "" {
+		index.StoreDocumentFields(docId, core.StoredDocument{Text: text})
+	}
+	return docId
+}
+
+func runSubstringQueryHelper(t *testing.T, index *NaiveReverseIndex, queryString string) []core.SearchResult {
+	fragments, err := SplitQuery(queryString)
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+	query, err := ParseQuery(fragments)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	results := NewBoundedResultSet(100)
+	if err := ExecuteContext(ctx, query, index, results, ExecutionConfig{}); err != nil {
+		t.Fatalf("Failed to execute query: %v", err)
+	}
+	return results.SortedSlice()
+}
+
+func TestSubstringQuery(t *testing.T) {
+	index := NewNaiveReverseIndex()
+	first := storeSubstringTestDocument(index, "replace XK-45210 now", "replace", "xk-45210", "now")
+	second := storeSubstringTestDocument(index, "spare AB-4521", "spare", "ab-4521")
+	storeSubstringTestDocument(index, "spare AB-452", "spare", "ab-452")
+
+	results := runSubstringQueryHelper(t, index, "'4521'")
+	docIds := []core.DocumentId{}
+	for _, result := range results {
+		docIds = append(docIds, result.DocId)
+		for token := range result.InvolvedTokens.Iterate() {
+			if token.StemmedText != "4521" || token.Position != 1 {
+				t.Errorf("Expected the substring to be involved at position 1, got %v", token)
+			}
+		}
+	}
+	slices.Sort(docIds)
+	if !slices.Equal(docIds, []core.DocumentId{first, second}) {
+		t.Errorf("Expected documents %d and %d, got %v", first, second, docIds)
+	}
+
+	if results := runSubstringQueryHelper(t, index, "'XK-452' AND now"); len(results) != 1 || results[0].DocId != first {
+		t.Errorf("Expected the substring to be case-insensitive and composable, got %v", results)
+	}
+}
+
+func TestSubstringQueryVerifiesStoredText(t *testing.T) {
+	index := NewNaiveReverseIndex()
+	storeSubstringTestDocument(index, "abcab", "abcab")
+	unverifiable := storeSubstringTestDocument(index, "", "abcab")
+	verified := storeSubstringTestDocument(index, "xbcabc", "xbcabc")
+
+	results := runSubstringQueryHelper(t, index, "'bcabc'")
+	docIds := []core.DocumentId{}
+	for _, result := range results {
+		docIds = append(docIds, result.DocId)
+	}
+	slices.Sort(docIds)
+	if !slices.Equal(docIds, []core.DocumentId{unverifiable, verified}) {
+		t.Errorf("Expected the false positive to be discarded, got %v", docIds)
+	}
+}
+
+func TestParseInvalidSubstringQuery(t *testing.T) {
+	for _, queryString := range []string{"'ab'", "'xk 4521'"} {
+		fragments, err := SplitQuery(queryString)
+		if err != nil {
+			t.Fatalf("Failed to split query: %v", err)
+		}
+		if _, err := ParseQuery(fragments); err == nil {
+			t.Errorf("Expected query %s to be rejected", queryString)
+		}
+	}
+	if _, err := SplitQuery("'4521"); err == nil {
+		t.Errorf("Expected an unterminated substring to be rejected")
+	}
+}
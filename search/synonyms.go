@@ -0,0 +1,185 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+This file contains the implementation of the query-time synonym expansion. A
+"SynonymDictionary" maps a (lower-case) word, or a sequence of words, to all of its
+alternatives. It is loaded from a text file with one rule per line ('#' starts a
+comment): "car, automobile, motor car" declares a group of equivalent expressions,
+each one expanding into all the others, while "ny => new york, nyc" declares that
+the expressions on the left are replaced by the ones on the right (to be kept, the
+expressions on the left must be repeated on the right as well).
+
+ExpandSynonyms rewrites every term (and every quoted phrase) of a query having some
+synonyms into a parenthesized group of alternatives joined by OR operators, which
+the parser turns into a sub-tree of the query: "car" becomes "( car OR automobile
+OR "motor car" )". Multi-word synonyms become quoted phrases. The expansion must run
+before the query is analyzed, so that the synonyms are stemmed like any other term.
+Still, terms are looked up both as they are and as analyzed by the query analyzers:
+the expressions of the dictionary are run through the very same analyzers, so that
+"cars" expands like "car" does (and "automobiles" matches the rule as well).
+Fuzzy terms and wildcard patterns are never expanded.
+==================================================================================*/
+
+package search
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"maps"
+	"quinto/data"
+	"slices"
+	"strings"
+)
+
+type SynonymDictionary map[string][]string
+
+func normalizeSynonym(expression string) string {
+	return strings.Join(strings.Fields(strings.ToLower(expression)), " ")
+}
+
+func parseSynonymExpressions(list string) []string {
+	expressions := []string{}
+	for _, expression := range strings.Split(list, ",") {
+		if expression = normalizeSynonym(expression); expression != "" && !slices.Contains(expressions, expression) {
+			expressions = append(expressions, expression)
+		}
+	}
+	return expressions
+}
+
+func (d SynonymDictionary) addSynonyms(expression string, synonyms []string) {
+	for _, synonym := range synonyms {
+		if !slices.Contains(d[expression], synonym) {
+			d[expression] = append(d[expression], synonym)
+		}
+	}
+}
+
+func (d SynonymDictionary) AddRule(rule string) error {
+	if index := strings.IndexByte(rule, '#'); index >= 0 {
+		rule = rule[:index]
+	}
+	if strings.TrimSpace(rule) == "" {
+		return nil
+	}
+	if lx, rx, isMapping := strings.Cut(rule, "=>"); isMapping {
+		sources, targets := parseSynonymExpressions(lx), parseSynonymExpressions(rx)
+		if len(sources) == 0 || len(targets) == 0 {
+			return errors.New("invalid synonym rule: " + rule)
+		}
+		for _, source := range sources {
+			d.addSynonyms(source, targets)
+		}
+		return nil
+	}
+	group := parseSynonymExpressions(rule)
+	if len(group) < 2 {
+		return errors.New("invalid synonym rule: " + rule)
+	}
+	for _, expression := range group {
+		d.addSynonyms(expression, group)
+	}
+	return nil
+}
+
+func LoadSynonymDictionary(reader io.Reader) (SynonymDictionary, error) {
+	dictionary := SynonymDictionary{}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		if err := dictionary.AddRule(scanner.Text()); err != nil {
+			return nil, err
+		}
+	}
+	return dictionary, scanner.Err()
+}
+
+func synonymKey(fragment queryFragment) (string, bool) {
+	if isPhraseFragment(fragment) {
+		return strings.Join(phraseWords(fragment), " "), true
+	}
+	if isTermFragment(fragment) && fragment.opt == 0 && !isWildcardPattern(fragment.txt) {
+		return fragment.txt, true
+	}
+	return "", false
+}
+
+func synonymFragment(synonym string) queryFragment {
+	if strings.Contains(synonym, " ") {
+		return queryFragment{`"` + synonym + `"`, false, 0}
+	}
+	return queryFragment{synonym, false, 0}
+}
+
+func analyzeSynonymKey(key string, analyzer QueryAnalyzer) string {
+	stemmedWords := []string{}
+	for token := range analyzer(data.NewSliceIterator(strings.Fields(key))) {
+		stemmedWords = append(stemmedWords, token.StemmedText)
+	}
+	return strings.Join(stemmedWords, " ")
+}
+
+func (d SynonymDictionary) analyzeKeys(analyzer QueryAnalyzer) SynonymDictionary {
+	analyzed := SynonymDictionary{}
+	for _, expression := range slices.Sorted(maps.Keys(d)) {
+		if key := analyzeSynonymKey(expression, analyzer); key != "" {
+			analyzed.addSynonyms(key, d[expression])
+		}
+	}
+	return analyzed
+}
+
+func lookupSynonyms(
+	key string,
+	dictionary SynonymDictionary,
+	analyzers []QueryAnalyzer,
+	analyzedDictionaries []SynonymDictionary,
+) []string {
+	synonyms := slices.Clone(dictionary[key])
+	for index, analyzer := range analyzers {
+		analyzedKey := analyzeSynonymKey(key, analyzer)
+		if analyzedKey == "" {
+			continue
+		}
+		for _, synonym := range analyzedDictionaries[index][analyzedKey] {
+			if !slices.Contains(synonyms, synonym) {
+				synonyms = append(synonyms, synonym)
+			}
+		}
+	}
+	return synonyms
+}
+
+func ExpandSynonyms(fragments []queryFragment, dictionary SynonymDictionary, analyzers []QueryAnalyzer) []queryFragment {
+	analyzedDictionaries := []SynonymDictionary{}
+	for _, analyzer := range analyzers {
+		analyzedDictionaries = append(analyzedDictionaries, dictionary.analyzeKeys(analyzer))
+	}
+	expanded := make([]queryFragment, 0, len(fragments))
+	for _, fragment := range fragments {
+		key, expandable := synonymKey(fragment)
+		if !expandable {
+			expanded = append(expanded, fragment)
+			continue
+		}
+		synonyms := lookupSynonyms(key, dictionary, analyzers, analyzedDictionaries)
+		if len(synonyms) == 0 {
+			expanded = append(expanded, fragment)
+			continue
+		}
+		expanded = append(expanded, queryFragment{"(", false, 0})
+		for index, synonym := range synonyms {
+			if index > 0 {
+				expanded = append(expanded, queryFragment{"OR", false, 0})
+			}
+			expanded = append(expanded, synonymFragment(synonym))
+		}
+		expanded = append(expanded, queryFragment{")", false, 0})
+	}
+	return expanded
+}
@@ -0,0 +1,128 @@
+package search
+
+import (
+	"context"
+	"quinto/data"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSynonymRules = `
+# equivalent expressions
+guitar, axe, six string
+hobby,pastime
+# one-directional rules
+tool => instrument, tool
+`
+
+func TestLoadSynonymDictionary(t *testing.T) {
+	dictionary, err := LoadSynonymDictionary(strings.NewReader(testSynonymRules))
+	if err != nil {
+		t.Fatalf("Failed to load synonyms: %v", err)
+	}
+
+	expected := map[string][]string{
+		"guitar":     {"guitar", "axe", "six string"},
+		"six string": {"guitar", "axe", "six string"},
+		"pastime":    {"hobby", "pastime"},
+		"tool":       {"instrument", "tool"},
+	}
+	for expression, synonyms := range expected {
+		if !slices.Equal(dictionary[expression], synonyms) {
+			t.Errorf("Expected synonyms %v for %q, got %v", synonyms, expression, dictionary[expression])
+		}
+	}
+	if _, exists := dictionary["instrument"]; exists {
+		t.Errorf("Expected one-directional rules not to be reversed")
+	}
+}
+
+func TestLoadInvalidSynonymRules(t *testing.T) {
+	for _, rules := range []string{"guitar", "guitar =>", "=> guitar", "guitar, ,"} {
+		if _, err := LoadSynonymDictionary(strings.NewReader(rules)); err == nil {
+			t.Errorf("Expected rule %q to be rejected", rules)
+		}
+	}
+}
+
+func TestExpandSynonyms(t *testing.T) {
+	dictionary, _ := LoadSynonymDictionary(strings.NewReader(testSynonymRules))
+	fragments, err := SplitQuery(`"six string" AND tool~1 AND NOT hobb*`)
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+
+	expanded := ExpandSynonyms(fragments, dictionary, nil)
+	expected := []queryFragment{
+		{"(", false, 0},
+		{"guitar", false, 0},
+		{"OR", false, 0},
+		{"axe", false, 0},
+		{"OR", false, 0},
+		{`"six string"`, false, 0},
+		{")", false, 0},
+		{"AND", false, 0},
+		{"tool", false, 1},
+		{"AND", false, 0},
+		{"NOT", false, 0},
+		{"hobb*", false, 0},
+	}
+
+	if !slices.Equal(expanded, expected) {
+		t.Errorf("Expected fragments %v, got %v", expected, expanded)
+	}
+}
+
+func TestExpandSynonymsOfAnalyzedTerms(t *testing.T) {
+	dictionary, _ := LoadSynonymDictionary(strings.NewReader("car, automobile\nbig apple => new york"))
+	fragments, err := SplitQuery(`cars AND "big apples" AND automobiles~1`)
+	if err != nil {
+		t.Fatalf("Failed to split query: %v", err)
+	}
+
+	expanded := ExpandSynonyms(fragments, dictionary, []QueryAnalyzer{pluralTrimmingAnalyzer})
+	expected := []queryFragment{
+		{"(", false, 0},
+		{"car", false, 0},
+		{"OR", false, 0},
+		{"automobile", false, 0},
+		{")", false, 0},
+		{"AND", false, 0},
+		{"(", false, 0},
+		{`"new york"`, false, 0},
+		{")", false, 0},
+		{"AND", false, 0},
+		{"automobiles", false, 1},
+	}
+
+	if !slices.Equal(expanded, expected) {
+		t.Errorf("Expected fragments %v, got %v", expected, expanded)
+	}
+}
+
+func TestSynonymQueryOverMultipleDocuments(t *testing.T) {
+	index := NewNaiveReverseIndex()
+	index.StoreNewDocument(data.NewSliceIterator(guitarDocument))
+	index.StoreNewDocument(data.NewSliceIterator(hobbyDocument))
+	index.StoreNewDocument(data.NewSliceIterator(toolsDocument))
+
+	dictionary, _ := LoadSynonymDictionary(strings.NewReader(testSynonymRules))
+	fragments, _ := SplitQuery("tool AND NOT music")
+	query, err := ParseQuery(ExpandSynonyms(fragments, dictionary, nil))
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	results := NewBoundedResultSet(100)
+	if err := ExecuteContext(ctx, query, index, results, ExecutionConfig{}); err != nil {
+		t.Fatalf("Failed to execute query: %v", err)
+	}
+	if matches := results.SortedSlice(); len(matches) != 1 || matches[0].DocId != 3 {
+		t.Errorf("Expected only the tools document to match, got %v", matches)
+	}
+}
@@ -0,0 +1,88 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+A "termUnionQuery" merges the inverted lists of many terms into a single stream of
+term occurrences, ordered by document-id and position, just like the one of a single
+term. It holds one query per term in a heap, sorted by their coordinates: the
+one on top is the next occurrence to be yielded. It is the building block of every
+query that expands into many terms (e.g. prefix, wildcard and fuzzy queries), which
+only need to provide the list of terms to be merged (as a list of "ExactQuery",
+possibly wrapped in a "BoostedQuery"). Every match carries the actual
+term that has been found, rather than the pattern that generated it.
+==================================================================================*/
+
+package search
+
+import (
+	"quinto/core"
+	"quinto/data"
+)
+
+type termUnionQuery struct {
+	queries *data.Heap[core.Query]
+}
+
+func queryComesBefore(lx, rx core.Query) bool {
+	lxDocId, lxPosition := lx.Coordinates()
+	rxDocId, rxPosition := rx.Coordinates()
+	return comesBefore(lxDocId, lxPosition, rxDocId, rxPosition)
+}
+
+func (q *termUnionQuery) init(index core.ReverseIndex, queries []core.Query) {
+	q.queries = data.NewHeap(queryComesBefore)
+	for _, query := range queries {
+		query.Init(index)
+		if query.Ended() {
+			query.Close()
+			continue
+		}
+		q.queries.Push(query)
+	}
+}
+
+func (q *termUnionQuery) Run() core.Match {
+	top, exists := q.queries.Peek()
+	if !exists {
+		return core.Match{Success: false}
+	}
+	return top.Run()
+}
+
+func (q *termUnionQuery) Advance() {
+	top, exists := q.queries.Pop()
+	if !exists {
+		return
+	}
+	top.Advance()
+	if top.Ended() {
+		top.Close()
+		return
+	}
+	q.queries.Push(top)
+}
+
+func (q *termUnionQuery) Ended() bool {
+	return q.queries == nil || q.queries.Size() == 0
+}
+
+func (q *termUnionQuery) Close() {
+	if q.queries == nil {
+		return
+	}
+	for query, exists := q.queries.Pop(); exists; query, exists = q.queries.Pop() {
+		query.Close()
+	}
+}
+
+func (q *termUnionQuery) Coordinates() (core.DocumentId, core.TermPosition) {
+	top, exists := q.queries.Peek()
+	if !exists {
+		return 0, 0
+	}
+	return top.Coordinates()
+}
@@ -0,0 +1,97 @@
+/*=================================== LICENSE =======================================
+
+                                   Apache License
+                             Version 2.0, January 2004
+                          http://www.apache.org/licenses/
+
+============================== BRIEF FILE DESCRIPTION ===============================
+
+A "WildcardQuery" query is a type of search query that matches every term fitting a
+pattern, where "*" stands for any sequence of characters (possibly empty) and "?"
+stands for exactly one character (e.g. "comput*" or "te?t"). Prefix queries are just
+wildcard queries ending with "*". When initialized, the pattern is expanded into the
+list of matching terms, by scanning the term dictionary of the reverse index from
+the literal prefix of the pattern (the part before the first wildcard), and the
+inverted lists of those terms are merged by a "termUnionQuery". Since a short
+pattern might expand into a huge number of terms, at most "maxExpansions" terms are
+taken into account (the first ones, in lexicographical order). "WildcardQuery"
+implements the Query interface, which defines the "Run", "Advance", and "Close"
+methods. Please refer to the documentation of the "Query" interface for more details
+about its methods and their intended usage.
+==================================================================================*/
+
+package search
+
+import (
+	"quinto/core"
+	"strings"
+)
+
+const DefaultMaxTermExpansions = 1024
+
+type WildcardQuery struct {
+	termUnionQuery
+	pattern       string
+	maxExpansions int
+}
+
+func NewWildcardQuery(pattern string, maxExpansions int) *WildcardQuery {
+	return &WildcardQuery{pattern: pattern, maxExpansions: maxExpansions}
+}
+
+func isWildcardPattern(text string) bool {
+	return strings.ContainsAny(text, "*?")
+}
+
+func literalPrefix(pattern string) string {
+	if index := strings.IndexAny(pattern, "*?"); index >= 0 {
+		return pattern[:index]
+	}
+	return pattern
+}
+
+func matchWildcard(pattern, text string) bool {
+	patternRunes, textRunes := []rune(pattern), []rune(text)
+	p, t := 0, 0
+	starPattern, starText := -1, 0
+	for t < len(textRunes) {
+		switch {
+		case p < len(patternRunes) && (patternRunes[p] == '?' || patternRunes[p] == textRunes[t]):
+			p++
+			t++
+		case p < len(patternRunes) && patternRunes[p] == '*':
+			starPattern, starText = p, t
+			p++
+		case starPattern >= 0:
+			starText++
+			p, t = starPattern+1, starText
+		default:
+			return false
+		}
+	}
+	for p < len(patternRunes) && patternRunes[p] == '*' {
+		p++
+	}
+	return p == len(patternRunes)
+}
+
+func (q *WildcardQuery) expand(index core.ReverseIndex) []string {
+	terms := []string{}
+	for term := range index.IterateOverDictionary(literalPrefix(q.pattern)) {
+		if len(terms) >= q.maxExpansions {
+			break
+		}
+		if matchWildcard(q.pattern, term) {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+func (q *WildcardQuery) Init(index core.ReverseIndex) {
+	queries := []core.Query{}
+	for _, term := range q.expand(index) {
+		queries = append(queries, &ExactQuery{term: term})
+	}
+	q.init(index, queries)
+}
@@ -0,0 +1,81 @@
+package search
+
+import (
+	"quinto/core"
+	"quinto/data"
+	"slices"
+	"testing"
+)
+
+func TestMatchWildcard(t *testing.T) {
+	cases := []struct {
+		pattern string
+		text    string
+		matches bool
+	}{
+		{"comput*", "comput", true},
+		{"comput*", "computer", true},
+		{"comput*", "compu", false},
+		{"te?t", "test", true},
+		{"te?t", "tet", false},
+		{"te?t", "teest", false},
+		{"*ing", "sing", true},
+		{"*ing", "singer", false},
+		{"a*b*c", "aXXbYYbc", true},
+		{"a*b*c", "aXXbYYbd", false},
+		{"caf?", "café", true},
+		{"*", "", true},
+	}
+	for _, c := range cases {
+		if matchWildcard(c.pattern, c.text) != c.matches {
+			t.Errorf("Expected matchWildcard(%q, %q) to be %v", c.pattern, c.text, c.matches)
+		}
+	}
+}
+
+func TestWildcardQueryExpansion(t *testing.T) {
+	index := NewNaiveReverseIndex()
+	index.StoreNewDocument(data.NewSliceIterator(createDummyDocument([]string{"test", "text", "tent", "toast", "tempest"})))
+
+	terms := NewWildcardQuery("te?t", DefaultMaxTermExpansions).expand(index)
+	if !slices.Equal(terms, []string{"tent", "test", "text"}) {
+		t.Errorf("Expected [tent test text], got %v", terms)
+	}
+
+	capped := NewWildcardQuery("t*", 2).expand(index)
+	if !slices.Equal(capped, []string{"tempest", "tent"}) {
+		t.Errorf("Expected the expansion to be capped to [tempest tent], got %v", capped)
+	}
+}
+
+func TestWildcardQueryMergesInvertedLists(t *testing.T) {
+	index := NewNaiveReverseIndex()
+	index.StoreNewDocument(data.NewSliceIterator(createDummyDocument([]string{"computer", "science", "compute"})))
+	index.StoreNewDocument(data.NewSliceIterator(createDummyDocument([]string{"hello"})))
+	index.StoreNewDocument(data.NewSliceIterator(createDummyDocument([]string{"computation", "computer"})))
+
+	query := NewWildcardQuery("comput*", DefaultMaxTermExpansions)
+	query.Init(index)
+	defer query.Close()
+
+	expected := []core.TermTracker{{DocId: 1, Position: 0}, {DocId: 1, Position: 2}, {DocId: 3, Position: 0}, {DocId: 3, Position: 1}}
+	expectedTerms := []string{"computer", "compute", "computation", "computer"}
+	found := []core.TermTracker{}
+	for !query.Ended() {
+		match := query.Run()
+		if !match.Success {
+			t.Fatalf("Expected every configuration to be a match")
+		}
+		for token := range match.InvolvedTokens.Iterate() {
+			if token.StemmedText != expectedTerms[len(found)] {
+				t.Errorf("Expected term %s, got %s", expectedTerms[len(found)], token.StemmedText)
+			}
+		}
+		found = append(found, core.TermTracker{DocId: match.DocId, Position: match.StartPosition})
+		query.Advance()
+	}
+
+	if !slices.Equal(found, expected) {
+		t.Errorf("Expected %v, got %v", expected, found)
+	}
+}
@@ -0,0 +1,156 @@
+package stemming
+
+import (
+	"fmt"
+	"iter"
+	"quinto/core"
+	"quinto/data"
+	"slices"
+	"sync"
+)
+
+// An Analyzer turns some text into the tokens to be indexed (or looked up, at query
+// time). The text is split into words by a Tokenizer, then every word becomes a token
+// whose "StemmedText" is rewritten (or dropped, or expanded into multiple tokens) by
+// an ordered chain of TokenFilters. Positions are assigned once the whole chain has
+// run: dropped tokens (e.g. stop-words) do not leave holes, while tokens placed by a
+// filter at the same position of another one (e.g. synonyms) keep sharing it.
+//
+// Analyzers are registered by name, so that the very same definition can be picked
+// both when indexing documents and when analyzing queries.
+
+type Tokenizer func(source iter.Seq[data.TextSpan]) iter.Seq[data.TextSpan]
+
+type TokenFilter func(tokens iter.Seq[core.Token]) iter.Seq[core.Token]
+
+type Analyzer struct {
+	Tokenizer Tokenizer
+	Filters   []TokenFilter
+}
+
+var analyzersMutex sync.RWMutex
+var analyzers = map[string]func() Analyzer{}
+
+func RegisterAnalyzer(name string, factory func() Analyzer) {
+	analyzersMutex.Lock()
+	defer analyzersMutex.Unlock()
+	if _, exists := analyzers[name]; exists {
+		panic(fmt.Sprintf("Analyzer already registered: %s", name))
+	}
+	analyzers[name] = factory
+}
+
+func LookupAnalyzer(name string) (Analyzer, bool) {
+	analyzersMutex.RLock()
+	defer analyzersMutex.RUnlock()
+	factory, exists := analyzers[name]
+	if !exists {
+		return Analyzer{}, false
+	}
+	return factory(), true
+}
+
+func AnalyzerNames() []string {
+	analyzersMutex.RLock()
+	defer analyzersMutex.RUnlock()
+	names := []string{}
+	for name := range analyzers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func WhitespaceTokenizer(source iter.Seq[data.TextSpan]) iter.Seq[data.TextSpan] {
+	if source == nil {
+		return func(yield func(data.TextSpan) bool) {}
+	}
+	return source
+}
+
+func newRawTokenIterator(words iter.Seq[data.TextSpan]) iter.Seq[core.Token] {
+	return func(yield func(core.Token) bool) {
+		position := core.TermPosition(0)
+		for word := range words {
+			if word.Text == "" {
+				continue
+			}
+			mustContinue := yield(core.Token{
+				Position:     position,
+				OriginalText: word.Text,
+				StemmedText:  word.Text,
+				StartOffset:  word.StartOffset,
+				EndOffset:    word.EndOffset,
+			})
+			if !mustContinue {
+				return
+			}
+			position++
+		}
+	}
+}
+
+func compactPositions(tokens iter.Seq[core.Token]) iter.Seq[core.Token] {
+	return func(yield func(core.Token) bool) {
+		position, previous, started := core.TermPosition(0), core.TermPosition(0), false
+		for token := range tokens {
+			if started && token.Position != previous {
+				position++
+			}
+			previous, started = token.Position, true
+			token.Position = position
+			if !yield(token) {
+				return
+			}
+		}
+	}
+}
+
+func (a Analyzer) Analyze(source iter.Seq[data.TextSpan]) iter.Seq[core.Token] {
+	tokenizer := a.Tokenizer
+	if tokenizer == nil {
+		tokenizer = WhitespaceTokenizer
+	}
+	tokens := newRawTokenIterator(tokenizer(source))
+	for _, filter := range a.Filters {
+		tokens = filter(tokens)
+	}
+	return compactPositions(tokens)
+}
+
+func EnglishAnalyzer() Analyzer {
+	return Analyzer{
+		Tokenizer: NewWordIterator,
+		Filters: []TokenFilter{
+			NFKCFilter,
+			LowercaseFilter,
+			ASCIIFoldingFilter,
+			NewStopWordFilter(stopWordsEnglish()),
+			NewStemmerFilter(stemEnglish),
+		},
+	}
+}
+
+func StandardAnalyzer() Analyzer {
+	return Analyzer{
+		Tokenizer: NewWordIterator,
+		Filters: []TokenFilter{
+			NFKCFilter,
+			LowercaseFilter,
+			ASCIIFoldingFilter,
+		},
+	}
+}
+
+func WhitespaceAnalyzer() Analyzer {
+	return Analyzer{
+		Tokenizer: WhitespaceTokenizer,
+		Filters:   []TokenFilter{LowercaseFilter},
+	}
+}
+
+func init() {
+	RegisterAnalyzer("english", EnglishAnalyzer)
+	RegisterAnalyzer("standard", StandardAnalyzer)
+	RegisterAnalyzer("whitespace", WhitespaceAnalyzer)
+}
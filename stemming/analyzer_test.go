@@ -0,0 +1,144 @@
+package stemming
+
+import (
+	"quinto/core"
+	"quinto/data"
+	"slices"
+	"testing"
+)
+
+func analyzeTestText(analyzer Analyzer, text string) ([]string, []core.TermPosition) {
+	terms, positions := []string{}, []core.TermPosition{}
+	for token := range analyzer.Analyze(data.NewTextSpanIterator(text)) {
+		terms = append(terms, token.StemmedText)
+		positions = append(positions, token.Position)
+	}
+	return terms, positions
+}
+
+func TestAnalyzerRegistry(t *testing.T) {
+	for _, name := range []string{"english", "standard", "whitespace"} {
+		if _, exists := LookupAnalyzer(name); !exists {
+			t.Errorf("Expected analyzer %s to be registered", name)
+		}
+	}
+	if _, exists := LookupAnalyzer("klingon"); exists {
+		t.Errorf("Expected unknown analyzers not to be found")
+	}
+
+	RegisterAnalyzer("test-short-words", func() Analyzer {
+		return Analyzer{Filters: []TokenFilter{LowercaseFilter, NewLengthFilter(1, 3)}}
+	})
+	analyzer, exists := LookupAnalyzer("test-short-words")
+	if !exists || !slices.Contains(AnalyzerNames(), "test-short-words") {
+		t.Fatalf("Expected the custom analyzer to be registered")
+	}
+	if terms, _ := analyzeTestText(analyzer, "The QUICK fox is RED"); !slices.Equal(terms, []string{"the", "fox", "is", "red"}) {
+		t.Errorf("Expected only the short words, got %v", terms)
+	}
+}
+
+func TestAnalyzerFiltersAreAppliedInOrder(t *testing.T) {
+	stopWords := data.ToSet([]string{"the"})
+	stopThenLowercase := Analyzer{Filters: []TokenFilter{NewStopWordFilter(stopWords), LowercaseFilter}}
+	lowercaseThenStop := Analyzer{Filters: []TokenFilter{LowercaseFilter, NewStopWordFilter(stopWords)}}
+
+	if terms, _ := analyzeTestText(stopThenLowercase, "The music"); !slices.Equal(terms, []string{"the", "music"}) {
+		t.Errorf("Expected the capitalized stop-word to survive, got %v", terms)
+	}
+	if terms, _ := analyzeTestText(lowercaseThenStop, "The music"); !slices.Equal(terms, []string{"music"}) {
+		t.Errorf("Expected the stop-word to be dropped, got %v", terms)
+	}
+}
+
+func TestAnalyzerPositions(t *testing.T) {
+	analyzer := Analyzer{
+		Tokenizer: NewWordIterator,
+		Filters: []TokenFilter{
+			LowercaseFilter,
+			NewStopWordFilter(data.ToSet([]string{"the", "of"})),
+			NewSynonymFilter(map[string][]string{"music": {"song", "tune"}}),
+		},
+	}
+
+	terms, positions := analyzeTestText(analyzer, "The music of the night")
+	if !slices.Equal(terms, []string{"music", "song", "tune", "night"}) {
+		t.Errorf("Expected the synonyms after the original term, got %v", terms)
+	}
+	if !slices.Equal(positions, []core.TermPosition{0, 0, 0, 1}) {
+		t.Errorf("Expected synonyms to share positions and stop-words not to leave holes, got %v", positions)
+	}
+}
+
+func TestNGramFilter(t *testing.T) {
+	analyzer := Analyzer{Filters: []TokenFilter{NewNGramFilter(2, 3)}}
+
+	terms, positions := analyzeTestText(analyzer, "café a")
+	expected := []string{"ca", "af", "fé", "caf", "afé", "a"}
+	if !slices.Equal(terms, expected) {
+		t.Errorf("Expected %v, got %v", expected, terms)
+	}
+	if !slices.Equal(positions, []core.TermPosition{0, 0, 0, 0, 0, 1}) {
+		t.Errorf("Expected the n-grams of a word to share its position, got %v", positions)
+	}
+}
+
+func TestEnglishAnalyzerMatchesEnglishTokenIterator(t *testing.T) {
+	text := "The guitars are Important instruments, aren't they?"
+	fromAnalyzer, _ := analyzeTestText(EnglishAnalyzer(), text)
+	fromIterator := []string{}
+	for token := range NewEnglishTokenIterator(data.NewTextSpanIterator(text)) {
+		fromIterator = append(fromIterator, token.StemmedText)
+	}
+	if !slices.Equal(fromAnalyzer, fromIterator) || len(fromAnalyzer) == 0 {
+		t.Errorf("Expected the same terms, got %v and %v", fromAnalyzer, fromIterator)
+	}
+}
+
+func TestSubstringNGramFilter(t *testing.T) {
+	analyzer := Analyzer{Filters: []TokenFilter{LowercaseFilter, NewSubstringNGramFilter(3)}}
+
+	terms, positions := analyzeTestText(analyzer, "XK-4545 at Café")
+	expected := []string{"xk-", "k-4", "-45", "454", "545", "caf", "afé"}
+	if !slices.Equal(terms, expected) {
+		t.Errorf("Expected %v, got %v", expected, terms)
+	}
+	if !slices.Equal(positions, []core.TermPosition{0, 0, 0, 0, 0, 1, 1}) {
+		t.Errorf("Expected the n-grams of a word to share its position, got %v", positions)
+	}
+}
+
+func TestSubstringNGramsOfWordsSplitByTheAnalyzer(t *testing.T) {
+	text := "Serial XK-4521 of the engine"
+	analyzer := EnglishAnalyzer()
+	words := AlignWordsWithTokens(data.NewTextSpanIterator(text), analyzer.Analyze(data.NewTextSpanIterator(text)))
+
+	terms, positions := []string{}, []core.TermPosition{}
+	for token := range NewSubstringNGramFilter(3)(words) {
+		terms = append(terms, token.StemmedText)
+		positions = append(positions, token.Position)
+	}
+	expected := []string{"ser", "eri", "ria", "ial", "xk-", "k-4", "-45", "452", "521", "eng", "ngi", "gin", "ine"}
+	if !slices.Equal(terms, expected) {
+		t.Errorf("Expected %v, got %v", expected, terms)
+	}
+	expectedPositions := []core.TermPosition{0, 0, 0, 0, 1, 1, 1, 1, 1, 3, 3, 3, 3}
+	if !slices.Equal(positions, expectedPositions) {
+		t.Errorf("Expected the n-grams of a word to share the position of its first token, got %v", positions)
+	}
+}
+
+func TestStemmersLeaveTokensWithDigitsAlone(t *testing.T) {
+	for _, name := range []string{"english", "italian"} {
+		analyzer, _ := LookupAnalyzer(name)
+		terms, _ := analyzeTestText(analyzer, "1999 2000 marker300 marker30 mp3")
+		expected := []string{"1999", "2000", "marker300", "marker30", "mp3"}
+		if !slices.Equal(terms, expected) {
+			t.Errorf("Expected the %s analyzer to leave %v alone, got %v", name, expected, terms)
+		}
+	}
+	terms, _ := analyzeTestText(EnglishAnalyzer(), "running dogs")
+	if !slices.Equal(terms, []string{"run", "dog"}) {
+		t.Errorf("Expected words to be stemmed, got %v", terms)
+	}
+}
@@ -2,6 +2,7 @@ package stemming
 
 import (
 	"strings"
+	"unicode/utf8"
 )
 
 type stemmingPattern struct {
@@ -13,7 +14,7 @@ type stemmingPattern struct {
 
 func matchAndReplace(text string, patterns []stemmingPattern) string {
 	for _, pattern := range patterns {
-		textLen := len(text)
+		textLen := utf8.RuneCountInString(text)
 		if textLen < pattern.minLen {
 			continue
 		}
@@ -29,8 +30,9 @@ func matchAndReplace(text string, patterns []stemmingPattern) string {
 
 func hasVowelBeforeLastNChars(text string, lastChars int) bool {
 	vowels := "aeiouyAEIOUY"
-	for index, char := range text {
-		valid := index < len(text)-lastChars
+	runes := []rune(text)
+	for index, char := range runes {
+		valid := index < len(runes)-lastChars
 		isVowel := strings.ContainsRune(vowels, char)
 		if valid && isVowel {
 			return true
@@ -41,20 +43,19 @@ func hasVowelBeforeLastNChars(text string, lastChars int) bool {
 
 func removeLastVowel(text string) string {
 	vowels := "aeiouyAEIOUY"
-	lastRune := rune(text[len(text)-1])
-	lastChar := string(text[len(text)-1])
-	if strings.ContainsRune(vowels, lastRune) {
-		return strings.TrimSuffix(text, lastChar)
+	lastRune, lastSize := utf8.DecodeLastRuneInString(text)
+	if lastSize > 0 && strings.ContainsRune(vowels, lastRune) {
+		return text[:len(text)-lastSize]
 	}
 	return text
 }
 
 func removeLastRepeatedLetter(text string) string {
-	if len(text) > 2 {
-		lastChar := string(text[len(text)-1])
-		prevChar := string(text[len(text)-2])
-		if lastChar == prevChar {
-			return strings.TrimSuffix(text, lastChar)
+	if utf8.RuneCountInString(text) > 2 {
+		lastRune, lastSize := utf8.DecodeLastRuneInString(text)
+		prevRune, _ := utf8.DecodeLastRuneInString(text[:len(text)-lastSize])
+		if lastRune == prevRune {
+			return text[:len(text)-lastSize]
 		}
 	}
 	return text
@@ -0,0 +1,185 @@
+package stemming
+
+import (
+	"iter"
+	"quinto/core"
+	"quinto/data"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// The token filters that can be chained by an Analyzer. Every filter works on the
+// "StemmedText" of the tokens, leaving the "OriginalText" (and the offsets) alone,
+// so that the tokens can always be traced back to the source text.
+
+func NewTokenMapFilter(transform func(string) string) TokenFilter {
+	return func(tokens iter.Seq[core.Token]) iter.Seq[core.Token] {
+		return func(yield func(core.Token) bool) {
+			for token := range tokens {
+				token.StemmedText = transform(token.StemmedText)
+				if !yield(token) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func newTokenDropFilter(mustDrop func(core.Token) bool) TokenFilter {
+	return func(tokens iter.Seq[core.Token]) iter.Seq[core.Token] {
+		return func(yield func(core.Token) bool) {
+			for token := range tokens {
+				if !mustDrop(token) && !yield(token) {
+					return
+				}
+			}
+		}
+	}
+}
+
+var NFKCFilter = NewTokenMapFilter(norm.NFKC.String)
+
+var LowercaseFilter = NewTokenMapFilter(strings.ToLower)
+
+var ASCIIFoldingFilter = NewTokenMapFilter(foldDiacritics)
+
+// Stemmers are only meant for words: tokens containing digits (e.g. "1999", or
+// identifiers such as "mp3" and "marker300") are left alone, since stemming them
+// would conflate distinct numbers.
+func NewStemmerFilter(stemmer func(string) string) TokenFilter {
+	return NewTokenMapFilter(func(text string) string {
+		if strings.ContainsFunc(text, unicode.IsDigit) {
+			return text
+		}
+		return stemmer(text)
+	})
+}
+
+func NewStopWordFilter(stopWords data.Set[string]) TokenFilter {
+	return newTokenDropFilter(func(token core.Token) bool {
+		return token.StemmedText == "" || stopWords.Contains(token.StemmedText)
+	})
+}
+
+func NewLengthFilter(minLength int, maxLength int) TokenFilter {
+	return newTokenDropFilter(func(token core.Token) bool {
+		length := utf8.RuneCountInString(token.StemmedText)
+		return length < minLength || (maxLength > 0 && length > maxLength)
+	})
+}
+
+func NewSynonymFilter(synonyms map[string][]string) TokenFilter {
+	return func(tokens iter.Seq[core.Token]) iter.Seq[core.Token] {
+		return func(yield func(core.Token) bool) {
+			for token := range tokens {
+				if !yield(token) {
+					return
+				}
+				for _, synonym := range synonyms[token.StemmedText] {
+					token.StemmedText = synonym
+					if !yield(token) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+func NewNGramFilter(minSize int, maxSize int) TokenFilter {
+	return func(tokens iter.Seq[core.Token]) iter.Seq[core.Token] {
+		return func(yield func(core.Token) bool) {
+			for token := range tokens {
+				runes := []rune(token.StemmedText)
+				if len(runes) < minSize {
+					if !yield(token) {
+						return
+					}
+					continue
+				}
+				for size := minSize; size <= min(maxSize, len(runes)); size++ {
+					for start := 0; start+size <= len(runes); start++ {
+						token.StemmedText = string(runes[start : start+size])
+						if !yield(token) {
+							return
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func NewElisionFilter(articles data.Set[string]) TokenFilter {
+	return NewTokenMapFilter(func(text string) string {
+		if index := strings.IndexAny(text, "'’"); index >= 0 && articles.Contains(text[:index]) {
+			_, size := utf8.DecodeRuneInString(text[index:])
+			return text[index+size:]
+		}
+		return text
+	})
+}
+
+// Unlike the other filters, the substring n-gram filter works on the "OriginalText"
+// (lower-cased) of the tokens, so that the n-grams of a word are the same whatever
+// stemmer has been used. The n-grams of a word share its position, and every n-gram
+// is yielded once per word. Words shorter than the n-grams are dropped. It is meant
+// to be fed with the whitespace-separated words of a text, as they are (see
+// AlignWordsWithTokens), so that a substring can span the many tokens a word might
+// be split into (e.g. "xk-4521" is made of the tokens "xk" and "4521").
+func NewSubstringNGramFilter(size int) TokenFilter {
+	return func(tokens iter.Seq[core.Token]) iter.Seq[core.Token] {
+		return func(yield func(core.Token) bool) {
+			for token := range tokens {
+				runes := []rune(strings.ToLower(token.OriginalText))
+				yielded := data.NewSet[string]()
+				for start := 0; start+size <= len(runes); start++ {
+					token.StemmedText = string(runes[start : start+size])
+					if yielded.Contains(token.StemmedText) {
+						continue
+					}
+					yielded.InsertOne(token.StemmedText)
+					if !yield(token) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// The whitespace-separated words of a text, each one as a token placed at the
+// position of the first token (among the given ones, which must come from the same
+// text, in order) that lies within the word. Words without any token (e.g. made of
+// stop-words or punctuation only) are dropped.
+func AlignWordsWithTokens(words iter.Seq[data.TextSpan], tokens iter.Seq[core.Token]) iter.Seq[core.Token] {
+	return func(yield func(core.Token) bool) {
+		nextToken, stop := iter.Pull(tokens)
+		defer stop()
+		token, exists := nextToken()
+		for word := range words {
+			for exists && token.StartOffset < word.StartOffset {
+				token, exists = nextToken()
+			}
+			if !exists {
+				return
+			}
+			if token.StartOffset >= word.EndOffset {
+				continue
+			}
+			mustContinue := yield(core.Token{
+				Position:     token.Position,
+				OriginalText: word.Text,
+				StemmedText:  word.Text,
+				StartOffset:  word.StartOffset,
+				EndOffset:    word.EndOffset,
+			})
+			if !mustContinue {
+				return
+			}
+		}
+	}
+}
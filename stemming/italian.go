@@ -0,0 +1,244 @@
+package stemming
+
+import (
+	"cmp"
+	"quinto/data"
+	"slices"
+	"strings"
+)
+
+// The Italian stemmer follows the steps of the Snowball one: attached pronouns are
+// removed first (e.g. "mangiandolo" -> "mangiando", "mangiarlo" -> "mangiare"),
+// then the standard (derivational) suffixes and, only when none of them matched, the
+// verb suffixes. Finally the last vowel is dropped, and "ch"/"gh" are turned into
+// "c"/"g" (so that "amico" and "amiche" share the same stem). Instead of computing
+// the R1/R2/RV regions, the patterns require a minimum length of the whole word.
+// Accents are expected to be already folded (e.g. "città" -> "citta").
+
+func stopWordsItalian() data.Set[string] {
+	return data.ToSet([]string{
+		"a", "ad", "al", "allo", "ai", "agli", "all", "agl", "alla", "alle", "con",
+		"col", "coi", "da", "dal", "dallo", "dai", "dagli", "dall", "dagl", "dalla",
+		"dalle", "di", "del", "dello", "dei", "degli", "dell", "degl", "della", "delle",
+		"in", "nel", "nello", "nei", "negli", "nell", "negl", "nella", "nelle", "su",
+		"sul", "sullo", "sui", "sugli", "sull", "sugl", "sulla", "sulle", "per", "tra",
+		"fra", "contro", "io", "tu", "lui", "lei", "noi", "voi", "loro", "mio", "mia",
+		"miei", "mie", "tuo", "tua", "tuoi", "tue", "suo", "sua", "suoi", "sue",
+		"nostro", "nostra", "nostri", "nostre", "vostro", "vostra", "vostri",
+		"vostre", "mi", "ti", "ci", "vi", "lo", "la", "li", "le", "gli", "ne", "il",
+		"i", "un", "uno", "una", "ma", "ed", "se", "perche", "anche", "come", "dov",
+		"dove", "che", "chi", "cui", "non", "piu", "quale", "quanto", "quanti",
+		"quanta", "quante", "quello", "quelli", "quella", "quelle", "questo",
+		"questi", "questa", "queste", "si", "tutto", "tutti", "e", "o", "ho", "hai",
+		"ha", "abbiamo", "avete", "hanno", "sono", "sei", "siamo", "siete", "era",
+		"erano", "essere", "avere", "stato", "stata", "gia", "poi", "cosi",
+	})
+}
+
+func elidedArticlesItalian() data.Set[string] {
+	return data.ToSet([]string{
+		"l", "un", "d", "c", "m", "t", "s", "v", "dell", "dall", "nell", "sull",
+		"all", "coll", "pell", "quest", "quell", "nessun", "buon", "sant", "tutt",
+	})
+}
+
+var attachedPronounsItalian = []string{
+	"gliela", "gliele", "glieli", "glielo", "gliene", "sene", "mela", "mele", "meli",
+	"melo", "mene", "tela", "tele", "teli", "telo", "tene", "cela", "cele", "celi",
+	"celo", "cene", "vela", "vele", "veli", "velo", "vene", "gli", "ci", "la", "le",
+	"li", "lo", "mi", "ne", "si", "ti", "vi",
+}
+
+var attachedPronounPatternsItalian = func() []stemmingPattern {
+	patterns := []stemmingPattern{}
+	for _, pronoun := range attachedPronounsItalian {
+		for ending, replacement := range map[string]string{
+			"ando": "ando", "endo": "endo", "ar": "are", "er": "ere", "ir": "ire",
+		} {
+			suffix := ending + pronoun
+			patterns = append(patterns, stemmingPattern{suffix: suffix, replacement: replacement, minLen: len(suffix) + 2})
+		}
+	}
+	slices.SortStableFunc(patterns, func(a, b stemmingPattern) int {
+		return cmp.Or(cmp.Compare(len(b.suffix), len(a.suffix)), strings.Compare(a.suffix, b.suffix))
+	})
+	return patterns
+}()
+
+func removeAttachedPronounItalian(text string) string {
+	return matchAndReplace(text, attachedPronounPatternsItalian)
+}
+
+func removeStandardSuffixItalian(text string) string {
+	return matchAndReplace(text, []stemmingPattern{
+		{suffix: "icazione", replacement: "", minLen: 11, maxLen: 0},
+		{suffix: "icazioni", replacement: "", minLen: 11, maxLen: 0},
+		{suffix: "atrice", replacement: "", minLen: 9, maxLen: 0},
+		{suffix: "atrici", replacement: "", minLen: 9, maxLen: 0},
+		{suffix: "azione", replacement: "", minLen: 9, maxLen: 0},
+		{suffix: "azioni", replacement: "", minLen: 9, maxLen: 0},
+		{suffix: "uzione", replacement: "u", minLen: 9, maxLen: 0},
+		{suffix: "uzioni", replacement: "u", minLen: 9, maxLen: 0},
+		{suffix: "usione", replacement: "u", minLen: 9, maxLen: 0},
+		{suffix: "usioni", replacement: "u", minLen: 9, maxLen: 0},
+		{suffix: "amento", replacement: "", minLen: 9, maxLen: 0},
+		{suffix: "amenti", replacement: "", minLen: 9, maxLen: 0},
+		{suffix: "imento", replacement: "", minLen: 9, maxLen: 0},
+		{suffix: "imenti", replacement: "", minLen: 9, maxLen: 0},
+		{suffix: "amente", replacement: "", minLen: 9, maxLen: 0},
+		{suffix: "mente", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "atore", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "atori", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "logia", replacement: "log", minLen: 7, maxLen: 0},
+		{suffix: "logie", replacement: "log", minLen: 7, maxLen: 0},
+		{suffix: "abile", replacement: "", minLen: 10, maxLen: 0},
+		{suffix: "abili", replacement: "", minLen: 10, maxLen: 0},
+		{suffix: "ibile", replacement: "", minLen: 10, maxLen: 0},
+		{suffix: "ibili", replacement: "", minLen: 10, maxLen: 0},
+		{suffix: "enza", replacement: "ente", minLen: 7, maxLen: 0},
+		{suffix: "enze", replacement: "ente", minLen: 7, maxLen: 0},
+		{suffix: "anza", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "anze", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "iche", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "ichi", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "ismo", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "ismi", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "ista", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "iste", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "isti", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "ante", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "anti", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "ita", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "oso", replacement: "", minLen: 6, maxLen: 0},
+		{suffix: "osi", replacement: "", minLen: 6, maxLen: 0},
+		{suffix: "osa", replacement: "", minLen: 6, maxLen: 0},
+		{suffix: "ose", replacement: "", minLen: 6, maxLen: 0},
+		{suffix: "ivo", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "ivi", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "iva", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "ive", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "ico", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "ici", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "ica", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "ice", replacement: "", minLen: 8, maxLen: 0},
+	})
+}
+
+func removeVerbSuffixItalian(text string) string {
+	return matchAndReplace(text, []stemmingPattern{
+		{suffix: "erebbero", replacement: "", minLen: 10, maxLen: 0},
+		{suffix: "irebbero", replacement: "", minLen: 10, maxLen: 0},
+		{suffix: "erebbe", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "irebbe", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "eranno", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "iranno", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "assero", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "essero", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "issero", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "eresti", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "iresti", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "eremmo", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "iremmo", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "ereste", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "ireste", replacement: "", minLen: 8, maxLen: 0},
+		{suffix: "eremo", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "iremo", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "erete", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "irete", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "avamo", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "evamo", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "ivamo", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "avano", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "evano", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "ivano", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "arono", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "erono", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "irono", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "ammo", replacement: "", minLen: 6, maxLen: 0},
+		{suffix: "emmo", replacement: "", minLen: 6, maxLen: 0},
+		{suffix: "immo", replacement: "", minLen: 6, maxLen: 0},
+		{suffix: "iamo", replacement: "", minLen: 6, maxLen: 0},
+		{suffix: "ando", replacement: "", minLen: 6, maxLen: 0},
+		{suffix: "endo", replacement: "", minLen: 6, maxLen: 0},
+		{suffix: "erai", replacement: "", minLen: 6, maxLen: 0},
+		{suffix: "irai", replacement: "", minLen: 6, maxLen: 0},
+		{suffix: "erei", replacement: "", minLen: 6, maxLen: 0},
+		{suffix: "irei", replacement: "", minLen: 6, maxLen: 0},
+		{suffix: "asse", replacement: "", minLen: 6, maxLen: 0},
+		{suffix: "esse", replacement: "", minLen: 6, maxLen: 0},
+		{suffix: "isse", replacement: "", minLen: 6, maxLen: 0},
+		{suffix: "ete", replacement: "", minLen: 6, maxLen: 0},
+		{suffix: "ate", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "ite", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "ute", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "ato", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "ito", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "uto", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "ati", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "iti", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "uti", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "ata", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "ita", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "uta", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "are", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "ere", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "ire", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "ava", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "eva", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "iva", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "avo", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "evo", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "ivo", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "ero", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "iro", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "era", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "ira", replacement: "", minLen: 7, maxLen: 0},
+		{suffix: "ano", replacement: "", minLen: 5, maxLen: 0},
+		{suffix: "ono", replacement: "", minLen: 5, maxLen: 0},
+	})
+}
+
+func removeFinalVowelItalian(text string) string {
+	text = matchAndReplace(text, []stemmingPattern{
+		{suffix: "ia", replacement: "", minLen: 4, maxLen: 0},
+		{suffix: "ie", replacement: "", minLen: 4, maxLen: 0},
+		{suffix: "ii", replacement: "", minLen: 4, maxLen: 0},
+		{suffix: "io", replacement: "", minLen: 4, maxLen: 0},
+		{suffix: "a", replacement: "", minLen: 3, maxLen: 0},
+		{suffix: "e", replacement: "", minLen: 3, maxLen: 0},
+		{suffix: "i", replacement: "", minLen: 3, maxLen: 0},
+		{suffix: "o", replacement: "", minLen: 3, maxLen: 0},
+	})
+	return matchAndReplace(text, []stemmingPattern{
+		{suffix: "ch", replacement: "c", minLen: 3, maxLen: 0},
+		{suffix: "gh", replacement: "g", minLen: 3, maxLen: 0},
+	})
+}
+
+func stemItalian(text string) string {
+	text = removeAttachedPronounItalian(text)
+	if stemmed := removeStandardSuffixItalian(text); stemmed != text {
+		text = stemmed
+	} else {
+		text = removeVerbSuffixItalian(text)
+	}
+	return removeFinalVowelItalian(text)
+}
+
+func ItalianAnalyzer() Analyzer {
+	return Analyzer{
+		Tokenizer: NewWordIterator,
+		Filters: []TokenFilter{
+			NFKCFilter,
+			LowercaseFilter,
+			ASCIIFoldingFilter,
+			NewElisionFilter(elidedArticlesItalian()),
+			NewStopWordFilter(stopWordsItalian()),
+			NewStemmerFilter(stemItalian),
+		},
+	}
+}
+
+func init() {
+	RegisterAnalyzer("italian", ItalianAnalyzer)
+}
@@ -0,0 +1,54 @@
+package stemming
+
+import (
+	"bufio"
+	"os"
+	"quinto/data"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestItalianStemmerCorpus(t *testing.T) {
+	file, err := os.Open("testdata/italian_stems.txt")
+	if err != nil {
+		t.Fatalf("Failed to open the test corpus: %v", err)
+	}
+	defer file.Close()
+
+	analyzer := ItalianAnalyzer()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		tokens := data.CollectAsSlice(analyzer.Analyze(data.NewTextSpanIterator(fields[0])))
+		if len(tokens) != 1 || tokens[0].StemmedText != fields[1] {
+			t.Errorf("Expected %q to be stemmed as %q, got %v", fields[0], fields[1], tokens)
+		}
+	}
+}
+
+func TestItalianAnalyzerStopWordsAndElisions(t *testing.T) {
+	tokens := data.CollectAsSlice(ItalianAnalyzer().Analyze(
+		data.NewTextSpanIterator("L'amico della città è arrivato all'università con un'amica"),
+	))
+	terms := []string{}
+	for _, token := range tokens {
+		terms = append(terms, token.StemmedText)
+	}
+	expected := []string{"amic", "citt", "arriv", "univers", "amic"}
+	if !slices.Equal(terms, expected) {
+		t.Errorf("Expected %v, got %v", expected, terms)
+	}
+	if tokens[0].OriginalText != "L'amico" {
+		t.Errorf("Expected the original text to keep the elided article, got %q", tokens[0].OriginalText)
+	}
+}
+
+func TestItalianAnalyzerIsRegistered(t *testing.T) {
+	if _, exists := LookupAnalyzer("italian"); !exists {
+		t.Errorf("Expected the italian analyzer to be registered")
+	}
+}
@@ -0,0 +1,137 @@
+package stemming
+
+import (
+	"embed"
+	"maps"
+	"path"
+	"quinto/data"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// The language of a text is identified by comparing its n-gram profile with the
+// profiles of the supported languages (Cavnar & Trenkle, "N-Gram-Based Text
+// Categorization"). A profile is the list of the most frequent n-grams (from 1 to 3
+// runes, words being padded with '_' on both sides) sorted by decreasing frequency.
+// The distance between two profiles is the "out-of-place" measure: the sum, over the
+// n-grams of the text, of how far each one is ranked from its rank in the language
+// profile (n-grams missing from the language profile get the maximum penalty).
+//
+// The language profiles are built, once, from the sample prose embedded in the
+// "profiles" directory: every file is named after the code of its language (e.g.
+// "eng.txt"), the very same code used to pick the analyzer of the language.
+//
+// Short texts share too few n-grams with any profile to be told apart reliably, so a
+// language is only detected when the text yields enough n-grams and the closest
+// profile beats the runner-up by a clear margin (relative to the best distance):
+// otherwise nothing is detected, and callers keep their default language.
+
+//go:embed profiles/*.txt
+var languageSamples embed.FS
+
+const languageProfileSize = 300
+const languageNGramMaxSize = 3
+const languageDetectionMaxWords = 1000
+const languageDetectionMinNGrams = 40
+const languageDetectionMinMargin = 0.1
+
+type languageProfile map[string]int
+
+var languageProfilesOnce sync.Once
+var languageProfiles map[string]languageProfile
+
+func countLanguageNGrams(text string, counts map[string]int) {
+	words := 0
+	for span := range NewWordIterator(data.NewTextSpanIterator(text)) {
+		word := strings.ToLower(span.Text)
+		if !strings.ContainsFunc(word, unicode.IsLetter) {
+			continue
+		}
+		runes := []rune("_" + word + "_")
+		for size := 1; size <= languageNGramMaxSize; size++ {
+			for start := 0; start+size <= len(runes); start++ {
+				if nGram := string(runes[start : start+size]); nGram != "_" {
+					counts[nGram]++
+				}
+			}
+		}
+		if words++; words >= languageDetectionMaxWords {
+			return
+		}
+	}
+}
+
+func newLanguageProfile(counts map[string]int) languageProfile {
+	nGrams := slices.SortedFunc(maps.Keys(counts), func(a, b string) int {
+		if counts[a] != counts[b] {
+			return counts[b] - counts[a]
+		}
+		return strings.Compare(a, b)
+	})
+	profile := languageProfile{}
+	for rank, nGram := range nGrams[:min(len(nGrams), languageProfileSize)] {
+		profile[nGram] = rank
+	}
+	return profile
+}
+
+func loadLanguageProfiles() {
+	languageProfiles = map[string]languageProfile{}
+	entries, err := languageSamples.ReadDir("profiles")
+	if err != nil {
+		panic(err)
+	}
+	for _, entry := range entries {
+		sample, err := languageSamples.ReadFile(path.Join("profiles", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		counts := map[string]int{}
+		countLanguageNGrams(string(sample), counts)
+		languageProfiles[strings.TrimSuffix(entry.Name(), ".txt")] = newLanguageProfile(counts)
+	}
+}
+
+func profilesDistance(text languageProfile, language languageProfile) int {
+	distance := 0
+	for nGram, rank := range text {
+		languageRank, exists := language[nGram]
+		if !exists {
+			distance += languageProfileSize
+			continue
+		}
+		distance += max(rank-languageRank, languageRank-rank)
+	}
+	return distance
+}
+
+func DetectableLanguages() []string {
+	languageProfilesOnce.Do(loadLanguageProfiles)
+	return slices.Sorted(maps.Keys(languageProfiles))
+}
+
+func DetectLanguage(text string) (string, bool) {
+	languageProfilesOnce.Do(loadLanguageProfiles)
+	counts := map[string]int{}
+	countLanguageNGrams(text, counts)
+	if len(counts) < languageDetectionMinNGrams {
+		return "", false
+	}
+	profile := newLanguageProfile(counts)
+	detected, bestDistance, runnerUpDistance := "", -1, -1
+	for _, language := range slices.Sorted(maps.Keys(languageProfiles)) {
+		distance := profilesDistance(profile, languageProfiles[language])
+		if bestDistance < 0 || distance < bestDistance {
+			runnerUpDistance = bestDistance
+			detected, bestDistance = language, distance
+		} else if runnerUpDistance < 0 || distance < runnerUpDistance {
+			runnerUpDistance = distance
+		}
+	}
+	if runnerUpDistance >= 0 && float64(runnerUpDistance-bestDistance) < languageDetectionMinMargin*float64(bestDistance) {
+		return "", false
+	}
+	return detected, detected != ""
+}
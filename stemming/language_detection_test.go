@@ -0,0 +1,44 @@
+package stemming
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	samples := map[string]string{
+		"The cat is sleeping on the sofa":                      "eng",
+		"Where is the nearest train station?":                  "eng",
+		"Il gatto dorme sul divano":                            "ita",
+		"Dove si trova la stazione più vicina?":                "ita",
+		"I bambini giocano in giardino mentre la mamma cucina": "ita",
+	}
+	for text, expected := range samples {
+		if language, detected := DetectLanguage(text); !detected || language != expected {
+			t.Errorf("Expected %q to be detected as %s, got %s", text, expected, language)
+		}
+	}
+}
+
+func TestDetectLanguageOfTooShortText(t *testing.T) {
+	for _, text := range []string{"", "ciao", "42 17", "   "} {
+		if language, detected := DetectLanguage(text); detected {
+			t.Errorf("Expected no language to be detected for %q, got %s", text, language)
+		}
+	}
+}
+
+func TestDetectLanguageOfShortAmbiguousText(t *testing.T) {
+	for _, text := range []string{"lazy dogs sleep all day", "dogs and cats", "common text uniqmarker1 qqqq"} {
+		if language, detected := DetectLanguage(text); detected {
+			t.Errorf("Expected no language to be detected for %q, got %s", text, language)
+		}
+	}
+}
+
+func TestDetectableLanguages(t *testing.T) {
+	languages := DetectableLanguages()
+	if !slices.Equal(languages, []string{"eng", "ita"}) {
+		t.Errorf("Expected the English and Italian profiles, got %v", languages)
+	}
+}
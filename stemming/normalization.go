@@ -0,0 +1,41 @@
+package stemming
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Diacritics are folded away by the ASCIIFoldingFilter ("café" and "cafe" become the
+// same term), usually after the NFKC normalization, which maps compatibility forms
+// (full-width letters, ligatures, superscripts, ...) to their canonical counterparts,
+// and after lower-casing. Only the diacritics of the Latin, Greek and Cyrillic
+// scripts are folded, since in other scripts (e.g. the dakuten of Japanese kana) the
+// combining marks change the meaning of the letters.
+
+var foldedLetters = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ł': "l",
+	'đ': "d", 'ð': "d", 'þ': "th", 'ı': "i", 'ħ': "h", 'ŧ': "t",
+}
+
+func isFoldableScript(r rune) bool {
+	return unicode.In(r, unicode.Latin, unicode.Greek, unicode.Cyrillic)
+}
+
+func foldDiacritics(text string) string {
+	builder := strings.Builder{}
+	foldable := false
+	for _, r := range norm.NFD.String(text) {
+		if unicode.Is(unicode.Mn, r) && foldable {
+			continue
+		}
+		foldable = isFoldableScript(r)
+		if folded, exists := foldedLetters[r]; exists {
+			builder.WriteString(folded)
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	return norm.NFC.String(builder.String())
+}
@@ -0,0 +1,66 @@
+package stemming
+
+import (
+	"quinto/data"
+	"testing"
+)
+
+func normalizeTestWord(text string) string {
+	for token := range StandardAnalyzer().Analyze(data.NewTextSpanIterator(text)) {
+		return token.StemmedText
+	}
+	return ""
+}
+
+func TestNormalizeText(t *testing.T) {
+	cases := map[string]string{
+		"Café":       "cafe",
+		"NAÏVE":      "naive",
+		"Ｆｕｌｌ":       "full",
+		"ﬁnance":     "finance",
+		"Straße":     "strasse",
+		"Ærøskøbing": "aeroskobing",
+		"Ἀθῆναι":     "αθηναι",
+		"ёлка":       "елка",
+		"が":          "が",
+	}
+	for text, expected := range cases {
+		if normalized := normalizeTestWord(text); normalized != expected {
+			t.Errorf("Expected %q to be normalized as %q, got %q", text, expected, normalized)
+		}
+	}
+}
+
+func TestStemmingHelpersAreRuneSafe(t *testing.T) {
+	if stemmed := removeLastVowel("здравствуйте"); stemmed != "здравствуйте" {
+		t.Errorf("Expected non-Latin vowels to be kept, got %q", stemmed)
+	}
+	if stemmed := removeLastVowel(""); stemmed != "" {
+		t.Errorf("Expected the empty string to be kept, got %q", stemmed)
+	}
+	if stemmed := removeLastRepeatedLetter("šušš"); stemmed != "šuš" {
+		t.Errorf("Expected the repeated multibyte letter to be removed, got %q", stemmed)
+	}
+	if hasVowelBeforeLastNChars("ññas", 2) {
+		t.Errorf("Expected the last 2 characters (not bytes) to be skipped")
+	}
+	if stemmed := matchAndReplace("ççness", []stemmingPattern{{suffix: "ness", minLen: 7}}); stemmed != "ççness" {
+		t.Errorf("Expected the length of the text to be measured in characters, got %q", stemmed)
+	}
+}
+
+func TestEnglishTokenIteratorFoldsAccents(t *testing.T) {
+	accented := data.CollectAsSlice(NewEnglishTokenIterator(data.NewTextSpanIterator("Café naïve")))
+	plain := data.CollectAsSlice(NewEnglishTokenIterator(data.NewTextSpanIterator("cafe naive")))
+	if len(accented) != 2 || len(plain) != 2 {
+		t.Fatalf("Expected 2 tokens each, got %v and %v", accented, plain)
+	}
+	for i := range accented {
+		if accented[i].StemmedText != plain[i].StemmedText {
+			t.Errorf("Expected %q and %q to be the same term", accented[i].OriginalText, plain[i].OriginalText)
+		}
+	}
+	if accented[0].OriginalText != "Café" {
+		t.Errorf("Expected the original text to be preserved, got %q", accented[0].OriginalText)
+	}
+}
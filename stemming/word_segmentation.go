@@ -0,0 +1,214 @@
+package stemming
+
+import (
+	"iter"
+	"quinto/data"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Word boundaries follow the rules of Unicode UAX #29 (WB4 to WB13b): letters,
+// numbers and their connectors stay together ("don't", "3.14", "U.S.A", "snake_case"),
+// while hyphens, slashes and any other punctuation break words. Ideographs are words
+// on their own. URLs and e-mail addresses are kept as single words instead.
+
+type wordBreakClass int
+
+const (
+	wbOther wordBreakClass = iota
+	wbALetter
+	wbHebrewLetter
+	wbNumeric
+	wbKatakana
+	wbIdeographic
+	wbExtendNumLet
+	wbMidLetter
+	wbMidNum
+	wbMidNumLet
+	wbSingleQuote
+	wbDoubleQuote
+	wbExtend
+)
+
+const midLetterRunes = ":··՟״‧︓﹕："
+const midNumRunes = ",;;։،؍٬߸⁄︐︔﹐﹔，；"
+const midNumLetRunes = ".‘’․﹒＇．"
+const leadingUrlPunctuation = "([{<\"'"
+const trailingUrlPunctuation = ".,;:!?)]}>\"'"
+
+var urlPattern = regexp.MustCompile(`^(?i:[a-z][a-z0-9+.-]*://|www\.)[^\s<>"]+$`)
+var emailPattern = regexp.MustCompile(`^[\pL\pN._%+-]+@[\pL\pN-]+(\.[\pL\pN-]+)+$`)
+
+type classifiedRune struct {
+	class       wordBreakClass
+	startOffset int
+	endOffset   int
+	isAlnum     bool
+}
+
+func wordBreakClassOf(r rune) wordBreakClass {
+	switch {
+	case r == '\'':
+		return wbSingleQuote
+	case r == '"':
+		return wbDoubleQuote
+	case strings.ContainsRune(midLetterRunes, r):
+		return wbMidLetter
+	case strings.ContainsRune(midNumRunes, r):
+		return wbMidNum
+	case strings.ContainsRune(midNumLetRunes, r):
+		return wbMidNumLet
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc, unicode.Cf):
+		return wbExtend
+	case unicode.Is(unicode.Katakana, r) || r == 'ー':
+		return wbKatakana
+	case unicode.In(r, unicode.Han, unicode.Hiragana):
+		return wbIdeographic
+	case unicode.Is(unicode.Hebrew, r) && unicode.IsLetter(r):
+		return wbHebrewLetter
+	case unicode.IsLetter(r):
+		return wbALetter
+	case unicode.IsDigit(r):
+		return wbNumeric
+	case unicode.Is(unicode.Pc, r):
+		return wbExtendNumLet
+	}
+	return wbOther
+}
+
+func isAHLetter(class wordBreakClass) bool {
+	return class == wbALetter || class == wbHebrewLetter
+}
+
+func isAlphanumeric(class wordBreakClass) bool {
+	return isAHLetter(class) || class == wbNumeric
+}
+
+func startsWord(class wordBreakClass) bool {
+	return isAlphanumeric(class) || class == wbKatakana || class == wbIdeographic || class == wbExtendNumLet
+}
+
+func joinsWords(previous wordBreakClass, next wordBreakClass) bool {
+	switch {
+	case isAlphanumeric(previous) && isAlphanumeric(next):
+		return true
+	case previous == wbHebrewLetter && next == wbSingleQuote:
+		return true
+	case previous == wbKatakana && next == wbKatakana:
+		return true
+	case next == wbExtendNumLet:
+		return isAlphanumeric(previous) || previous == wbKatakana || previous == wbExtendNumLet
+	case previous == wbExtendNumLet:
+		return isAlphanumeric(next) || next == wbKatakana
+	}
+	return false
+}
+
+func joinsWordsAcross(previous wordBreakClass, middle wordBreakClass, next wordBreakClass) bool {
+	midNumLetQ := middle == wbMidNumLet || middle == wbSingleQuote
+	switch {
+	case isAHLetter(previous) && isAHLetter(next):
+		return middle == wbMidLetter || midNumLetQ
+	case previous == wbHebrewLetter && next == wbHebrewLetter:
+		return middle == wbDoubleQuote
+	case previous == wbNumeric && next == wbNumeric:
+		return middle == wbMidNum || midNumLetQ
+	}
+	return false
+}
+
+func classifyRunes(span data.TextSpan) []classifiedRune {
+	runes := []classifiedRune{}
+	for index, r := range span.Text {
+		size := utf8.RuneLen(r)
+		if r == utf8.RuneError {
+			_, size = utf8.DecodeRuneInString(span.Text[index:])
+		}
+		class := wordBreakClassOf(r)
+		runes = append(runes, classifiedRune{
+			class:       class,
+			startOffset: span.StartOffset + index,
+			endOffset:   span.StartOffset + index + size,
+			isAlnum:     unicode.IsLetter(r) || unicode.IsDigit(r),
+		})
+	}
+	return runes
+}
+
+func nextNonExtendRune(runes []classifiedRune, from int) int {
+	for from < len(runes) && runes[from].class == wbExtend {
+		from++
+	}
+	return from
+}
+
+func segmentWords(span data.TextSpan) []data.TextSpan {
+	words := []data.TextSpan{}
+	runes := classifyRunes(span)
+	start, end, last, hasAlnum := -1, 0, wbOther, false
+	emit := func() {
+		if start >= 0 && hasAlnum {
+			words = append(words, data.TextSpan{
+				Text:        span.Text[start-span.StartOffset : end-span.StartOffset],
+				StartOffset: start,
+				EndOffset:   end,
+			})
+		}
+		start, hasAlnum = -1, false
+	}
+	for i := 0; i < len(runes); i++ {
+		current := runes[i]
+		if current.class == wbExtend {
+			end = max(end, current.endOffset)
+			continue
+		}
+		if start >= 0 && joinsWords(last, current.class) {
+			last, end, hasAlnum = current.class, current.endOffset, hasAlnum || current.isAlnum
+			continue
+		}
+		if next := nextNonExtendRune(runes, i+1); start >= 0 && next < len(runes) &&
+			joinsWordsAcross(last, current.class, runes[next].class) {
+			last, end, hasAlnum = runes[next].class, runes[next].endOffset, true
+			i = next
+			continue
+		}
+		emit()
+		if startsWord(current.class) {
+			start, end, last, hasAlnum = current.startOffset, current.endOffset, current.class, current.isAlnum
+		}
+	}
+	emit()
+	return words
+}
+
+func asUrlOrEmail(span data.TextSpan) (data.TextSpan, bool) {
+	trimmed := strings.TrimLeft(span.Text, leadingUrlPunctuation)
+	span.StartOffset += len(span.Text) - len(trimmed)
+	trimmed = strings.TrimRight(trimmed, trailingUrlPunctuation)
+	span.EndOffset = span.StartOffset + len(trimmed)
+	span.Text = trimmed
+	return span, urlPattern.MatchString(trimmed) || emailPattern.MatchString(trimmed)
+}
+
+func NewWordIterator(sourceTextIterator iter.Seq[data.TextSpan]) iter.Seq[data.TextSpan] {
+	return func(yield func(data.TextSpan) bool) {
+		if sourceTextIterator == nil {
+			return
+		}
+		for span := range sourceTextIterator {
+			if word, isUrlOrEmail := asUrlOrEmail(span); isUrlOrEmail {
+				if !yield(word) {
+					return
+				}
+				continue
+			}
+			for _, word := range segmentWords(span) {
+				if !yield(word) {
+					return
+				}
+			}
+		}
+	}
+}
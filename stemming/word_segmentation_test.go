@@ -0,0 +1,68 @@
+package stemming
+
+import (
+	"quinto/data"
+	"slices"
+	"testing"
+)
+
+func collectWords(text string) []string {
+	words := []string{}
+	for word := range NewWordIterator(data.NewTextSpanIterator(text)) {
+		if text[word.StartOffset:word.EndOffset] != word.Text {
+			panic("word offsets do not match the source text")
+		}
+		words = append(words, word.Text)
+	}
+	return words
+}
+
+func TestWordIteratorPunctuation(t *testing.T) {
+	words := collectWords(`Hello, world! "Quoted" (parenthesis) state-of-the-art and/or ... ok?`)
+	expected := []string{"Hello", "world", "Quoted", "parenthesis", "state", "of", "the", "art", "and", "or", "ok"}
+	if !slices.Equal(words, expected) {
+		t.Errorf("Expected %v, got %v", expected, words)
+	}
+}
+
+func TestWordIteratorApostrophesAndAbbreviations(t *testing.T) {
+	words := collectWords("Don't stop rock’n’roll at the U.S.A. dogs' house")
+	expected := []string{"Don't", "stop", "rock’n’roll", "at", "the", "U.S.A", "dogs", "house"}
+	if !slices.Equal(words, expected) {
+		t.Errorf("Expected %v, got %v", expected, words)
+	}
+}
+
+func TestWordIteratorNumbers(t *testing.T) {
+	words := collectWords("It costs $3.14, or 1,000.50 euros; v2.0 at 10:30 with snake_case")
+	expected := []string{"It", "costs", "3.14", "or", "1,000.50", "euros", "v2.0", "at", "10", "30", "with", "snake_case"}
+	if !slices.Equal(words, expected) {
+		t.Errorf("Expected %v, got %v", expected, words)
+	}
+}
+
+func TestWordIteratorUrlsAndEmails(t *testing.T) {
+	words := collectWords("See (https://example.com/docs?page=1). Write to john.doe@example.org, or www.example.com!")
+	expected := []string{"See", "https://example.com/docs?page=1", "Write", "to", "john.doe@example.org", "or", "www.example.com"}
+	if !slices.Equal(words, expected) {
+		t.Errorf("Expected %v, got %v", expected, words)
+	}
+}
+
+func TestWordIteratorNonLatinScripts(t *testing.T) {
+	words := collectWords("日本語 テキスト café naïve Привет мир")
+	expected := []string{"日", "本", "語", "テキスト", "café", "naïve", "Привет", "мир"}
+	if !slices.Equal(words, expected) {
+		t.Errorf("Expected %v, got %v", expected, words)
+	}
+}
+
+func TestEnglishTokenIteratorUsesWordBoundaries(t *testing.T) {
+	tokens := data.CollectAsSlice(NewEnglishTokenIterator(data.NewTextSpanIterator("The world, the music!")))
+	if len(tokens) != 2 || tokens[0].StemmedText != "world" || tokens[1].StemmedText != "music" {
+		t.Fatalf("Expected the tokens world and music, got %v", tokens)
+	}
+	if tokens[0].Position != 0 || tokens[1].Position != 1 || tokens[1].StartOffset != 15 || tokens[1].EndOffset != 20 {
+		t.Errorf("Expected positions and offsets to skip punctuation and stop words, got %v", tokens)
+	}
+}